@@ -0,0 +1,208 @@
+package container
+
+import "iter"
+
+// Monoid describes how to summarize a range of values of type T into a
+// value of type S. Measure summarizes a single value and Combine merges the
+// summaries of two adjacent ranges. Combine must be associative and
+// Identity must be its neutral element, so that the summary of a range
+// doesn't depend on the shape of the treap holding it.
+//
+// For example, summing integers:
+//
+//	Monoid[int, int]{
+//		Identity: 0,
+//		Measure:  func(v int) int { return v },
+//		Combine:  func(a, b int) int { return a + b },
+//	}
+type Monoid[T, S any] struct {
+	Identity S
+	Measure  func(v T) S
+	Combine  func(left, right S) S
+}
+
+// AugmentedTreap is a persistent ordered set where every node also keeps
+// the summary of its subtree under a Monoid. It is a Treap whose split,
+// join and set operations recompute the summary of every node they create,
+// so the aggregate of any range of values is available in O(log n) without
+// walking the range.
+//
+// All methods return new treaps; the receiver is never modified. The zero
+// value is not usable, use NewAugmentedTreap instead.
+type AugmentedTreap[T, S any] struct {
+	root     *Treap[augItem[T, S]]
+	compare  CompareFn[T]
+	monoid   Monoid[T, S]
+	priority PriorityFn[T]
+}
+
+// augItem is the value of an AugmentedTreap node along with the summary of
+// its subtree.
+type augItem[T, S any] struct {
+	value   T
+	summary S
+}
+
+// NewAugmentedTreap returns an empty treap ordered by c and summarized by
+// m. p picks the priority of inserted values, it is random if nil.
+func NewAugmentedTreap[T, S any](c CompareFn[T], m Monoid[T, S], p PriorityFn[T]) *AugmentedTreap[T, S] {
+	if p == nil {
+		p = RandomPriority[T]()
+	}
+	return &AugmentedTreap[T, S]{
+		compare:  c,
+		monoid:   m,
+		priority: p,
+	}
+}
+
+func (t *AugmentedTreap[T, S]) with(root *Treap[augItem[T, S]]) *AugmentedTreap[T, S] {
+	if root == t.root {
+		return t
+	}
+	return &AugmentedTreap[T, S]{
+		root:     root,
+		compare:  t.compare,
+		monoid:   t.monoid,
+		priority: t.priority,
+	}
+}
+
+// Len returns the number of values in the treap.
+func (t *AugmentedTreap[T, S]) Len() int {
+	return t.root.Len()
+}
+
+// Summary returns the summary of all values in the treap, or the identity
+// if it is empty.
+func (t *AugmentedTreap[T, S]) Summary() S {
+	return t.summaryOf(t.root)
+}
+
+// Contains returns true if the treap contains v.
+func (t *AugmentedTreap[T, S]) Contains(v T) bool {
+	_, ok := t.Get(v)
+	return ok
+}
+
+// Get returns the stored value that compares equal to v.
+func (t *AugmentedTreap[T, S]) Get(v T) (T, bool) {
+	if n := t.root.Find(augItem[T, S]{value: v}, t.order); n != nil {
+		return n.Value.value, true
+	}
+	var zv T
+	return zv, false
+}
+
+// Insert returns a treap with v added, replacing the value if it is
+// already present.
+func (t *AugmentedTreap[T, S]) Insert(v T) *AugmentedTreap[T, S] {
+	leaf := newTreap(augItem[T, S]{value: v}, t.priority(v), nil, nil, t.update)
+	return t.with(t.root.union(leaf, t.order, true, t.update))
+}
+
+// Delete returns a treap without v.
+func (t *AugmentedTreap[T, S]) Delete(v T) *AugmentedTreap[T, S] {
+	left, mid, right := t.root.split(augItem[T, S]{value: v}, t.order, t.update)
+	if mid == nil {
+		return t
+	}
+	return t.with(left.join(right, t.update))
+}
+
+// Split returns the treaps of the values that compare less-than and
+// greater-than v, and whether v itself was present.
+func (t *AugmentedTreap[T, S]) Split(v T) (left *AugmentedTreap[T, S], found bool, right *AugmentedTreap[T, S]) {
+	l, mid, r := t.root.split(augItem[T, S]{value: v}, t.order, t.update)
+	return t.with(l), mid != nil, t.with(r)
+}
+
+// Union returns a treap with the values of both treaps. See Treap.Union
+// for the meaning of overwrite. other must use the same comparer and
+// monoid.
+func (t *AugmentedTreap[T, S]) Union(other *AugmentedTreap[T, S], overwrite bool) *AugmentedTreap[T, S] {
+	return t.with(t.root.union(other.root, t.order, overwrite, t.update))
+}
+
+// Intersection returns a treap with the values present in both treaps.
+func (t *AugmentedTreap[T, S]) Intersection(other *AugmentedTreap[T, S]) *AugmentedTreap[T, S] {
+	edit := new(treapEdit)
+	return t.with(t.root.intersectionT(other.root, t.order, edit, t.update).disown(edit))
+}
+
+// Diff returns a treap with the values that are not present in other.
+func (t *AugmentedTreap[T, S]) Diff(other *AugmentedTreap[T, S]) *AugmentedTreap[T, S] {
+	return t.with(t.root.diff(other.root, t.order, t.update))
+}
+
+// Aggregate returns the summary of the values between lo and hi, with the
+// bounds controlling whether lo and hi themselves are included. It only
+// walks the two paths leading to lo and hi.
+func (t *AugmentedTreap[T, S]) Aggregate(lo, hi T, bounds RangeBounds) S {
+	return t.aggregate(t.root, lo, hi, bounds, true, true)
+}
+
+// All returns an iterator over the values in ascending order.
+func (t *AugmentedTreap[T, S]) All() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for v := range t.root.All() {
+			if !yield(v.value) {
+				return
+			}
+		}
+	}
+}
+
+// ForEach does inorder traversal of the treap.
+func (t *AugmentedTreap[T, S]) ForEach(fn func(v T)) {
+	t.root.ForEach(func(v augItem[T, S]) {
+		fn(v.value)
+	})
+}
+
+// order compares nodes by their values.
+func (t *AugmentedTreap[T, S]) order(left, right augItem[T, S]) int {
+	return t.compare(left.value, right.value)
+}
+
+// update recomputes the summary of n from its children.
+func (t *AugmentedTreap[T, S]) update(n *Treap[augItem[T, S]]) {
+	m := t.monoid
+	n.Value.summary = m.Combine(m.Combine(t.summaryOf(n.Left), m.Measure(n.Value.value)), t.summaryOf(n.Right))
+}
+
+func (t *AugmentedTreap[T, S]) summaryOf(n *Treap[augItem[T, S]]) S {
+	if n == nil {
+		return t.monoid.Identity
+	}
+	return n.Value.summary
+}
+
+// aggregate returns the summary of the values of n within the bounds.
+// checkLo and checkHi tell whether lo and hi can still cut through n; once
+// both are false the whole subtree is in range and its summary is used
+// as is.
+func (t *AugmentedTreap[T, S]) aggregate(n *Treap[augItem[T, S]], lo, hi T, bounds RangeBounds, checkLo, checkHi bool) S {
+	if n == nil {
+		return t.monoid.Identity
+	}
+	if !checkLo && !checkHi {
+		return n.Value.summary
+	}
+	if checkLo {
+		dlo := t.compare(n.Value.value, lo)
+		if dlo < 0 || (dlo == 0 && bounds&RangeIncludeLow == 0) {
+			return t.aggregate(n.Right, lo, hi, bounds, checkLo, checkHi)
+		}
+	}
+	if checkHi {
+		dhi := t.compare(n.Value.value, hi)
+		if dhi > 0 || (dhi == 0 && bounds&RangeIncludeHigh == 0) {
+			return t.aggregate(n.Left, lo, hi, bounds, checkLo, checkHi)
+		}
+	}
+	m := t.monoid
+	left := t.aggregate(n.Left, lo, hi, bounds, checkLo, false)
+	right := t.aggregate(n.Right, lo, hi, bounds, false, checkHi)
+	return m.Combine(m.Combine(left, m.Measure(n.Value.value)), right)
+}
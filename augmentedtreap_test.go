@@ -0,0 +1,151 @@
+package container_test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/gabstv/container"
+	"github.com/stretchr/testify/assert"
+)
+
+type sumMin struct {
+	Sum, Min, Count int
+}
+
+var sumMinMonoid = container.Monoid[int, sumMin]{
+	Identity: sumMin{Min: math.MaxInt},
+	Measure: func(v int) sumMin {
+		return sumMin{Sum: v, Min: v, Count: 1}
+	},
+	Combine: func(left, right sumMin) sumMin {
+		return sumMin{
+			Sum:   left.Sum + right.Sum,
+			Min:   min(left.Min, right.Min),
+			Count: left.Count + right.Count,
+		}
+	},
+}
+
+func bruteAggregate(values []int, lo, hi int, bounds container.RangeBounds) sumMin {
+	s := sumMinMonoid.Identity
+	for _, v := range values {
+		if v < lo || (v == lo && bounds&container.RangeIncludeLow == 0) {
+			continue
+		}
+		if v > hi || (v == hi && bounds&container.RangeIncludeHigh == 0) {
+			continue
+		}
+		s = sumMinMonoid.Combine(s, sumMinMonoid.Measure(v))
+	}
+	return s
+}
+
+func TestAugmentedTreapAggregate(t *testing.T) {
+	rand.Seed(42)
+
+	x := container.NewAugmentedTreap(intComparer[int], sumMinMonoid, nil)
+	assert.Equal(t, sumMinMonoid.Identity, x.Summary())
+
+	values := map[int]bool{}
+	for kk := 0; kk < 2000; kk++ {
+		v := rand.Intn(5000)
+		values[v] = true
+		x = x.Insert(v)
+	}
+	for kk := 0; kk < 500; kk++ {
+		v := rand.Intn(5000)
+		delete(values, v)
+		x = x.Delete(v)
+	}
+	all := []int{}
+	for v := range x.All() {
+		all = append(all, v)
+	}
+	assert.Equal(t, len(values), x.Len())
+	assert.Equal(t, len(values), len(all))
+	assert.Equal(t, bruteAggregate(all, 0, 5000, container.RangeClosed), x.Summary())
+
+	bounds := []container.RangeBounds{container.RangeOpen, container.RangeClosed, container.RangeIncludeLow, container.RangeIncludeHigh}
+	for kk := 0; kk < 500; kk++ {
+		lo := rand.Intn(5000)
+		hi := lo + rand.Intn(2000)
+		b := bounds[kk%len(bounds)]
+		assert.Equal(t, bruteAggregate(all, lo, hi, b), x.Aggregate(lo, hi, b))
+	}
+	for _, v := range all[:10] {
+		assert.True(t, x.Contains(v))
+	}
+}
+
+func TestAugmentedTreapSetOperations(t *testing.T) {
+	empty := container.NewAugmentedTreap(intComparer[int], sumMinMonoid, container.IntegerPriority[int]())
+	x, y := empty, empty
+	for kk := 0; kk < 100; kk++ {
+		x = x.Insert(kk)
+		y = y.Insert(kk + 50)
+	}
+
+	union := x.Union(y, false)
+	assert.Equal(t, sumMin{Sum: 149 * 150 / 2, Min: 0, Count: 150}, union.Summary())
+	inter := x.Intersection(y)
+	assert.Equal(t, sumMin{Sum: (50 + 99) * 50 / 2, Min: 50, Count: 50}, inter.Summary())
+	diff := x.Diff(y)
+	assert.Equal(t, sumMin{Sum: 49 * 50 / 2, Min: 0, Count: 50}, diff.Summary())
+	assert.Equal(t, 40, diff.Aggregate(40, 49, container.RangeIncludeLow).Min)
+
+	left, found, right := union.Split(75)
+	assert.True(t, found)
+	assert.Equal(t, 75, left.Len())
+	assert.Equal(t, 74, right.Len())
+	assert.Equal(t, 76, right.Summary().Min)
+	_, found, _ = union.Split(500)
+	assert.False(t, found)
+
+	// the originals are left untouched
+	assert.Equal(t, 100, x.Summary().Count)
+	assert.Equal(t, 100, y.Summary().Count)
+	assert.Same(t, x, x.Delete(1000))
+}
+
+func TestAugmentedTreapRandomSetOperations(t *testing.T) {
+	rand.Seed(7)
+
+	empty := container.NewAugmentedTreap(intComparer[int], sumMinMonoid, nil)
+	for round := 0; round < 20; round++ {
+		x, y := empty, empty
+		inX, inY := map[int]bool{}, map[int]bool{}
+		for kk := 0; kk < 300; kk++ {
+			a, b := rand.Intn(500), rand.Intn(500)
+			x, inX[a] = x.Insert(a), true
+			y, inY[b] = y.Insert(b), true
+		}
+
+		var union, inter, diff []int
+		for v := 0; v < 500; v++ {
+			if inX[v] || inY[v] {
+				union = append(union, v)
+			}
+			if inX[v] && inY[v] {
+				inter = append(inter, v)
+			}
+			if inX[v] && !inY[v] {
+				diff = append(diff, v)
+			}
+		}
+
+		lo, hi := rand.Intn(250), 250+rand.Intn(250)
+		for _, tc := range []struct {
+			got  *container.AugmentedTreap[int, sumMin]
+			want []int
+		}{
+			{x.Union(y, true), union},
+			{x.Intersection(y), inter},
+			{x.Diff(y), diff},
+		} {
+			assert.Equal(t, len(tc.want), tc.got.Len())
+			assert.Equal(t, bruteAggregate(tc.want, 0, 500, container.RangeClosed), tc.got.Summary())
+			assert.Equal(t, bruteAggregate(tc.want, lo, hi, container.RangeIncludeLow), tc.got.Aggregate(lo, hi, container.RangeIncludeLow))
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package container_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gabstv/container"
+	"github.com/stretchr/testify/assert"
+)
+
+func testCodec[T any](t *testing.T, c container.Codec[T], v T) {
+	t.Helper()
+	data, err := c.Encode(v)
+	assert.NoError(t, err)
+	got, err := c.Decode(data)
+	assert.NoError(t, err)
+	assert.Equal(t, v, got)
+}
+
+func TestCodecs(t *testing.T) {
+	key := TestKey{Name: "alpha", Score: 100}
+	testCodec(t, container.GobCodec[TestKey](), key)
+	testCodec(t, container.GobCodec[int](), 0)
+	testCodec(t, container.GobCodec[[]string](), []string{"a", "b"})
+	testCodec(t, container.JSONCodec[TestKey](), key)
+	testCodec(t, container.JSONCodec[string](), "")
+
+	now := time.Date(2022, 3, 4, 5, 6, 7, 8, time.UTC)
+	testCodec(t, container.BinaryCodec[time.Time](), now)
+
+	_, err := container.JSONCodec[int]().Decode([]byte("nope"))
+	assert.Error(t, err)
+	_, err = container.BinaryCodec[time.Time]().Decode([]byte{1})
+	assert.Error(t, err)
+}
@@ -1,11 +1,18 @@
 package container
 
-import "sync"
+import (
+	"iter"
+	"sync"
+)
 
 // Dictionary is a thread-safe dictionary.
 type Dictionary[KT comparable, VT any] struct {
-	lock sync.RWMutex
-	m    map[KT]VT
+	lock     sync.RWMutex
+	m        map[KT]VT
+	watchers []*DictWatcher[KT, VT]
+	// events are queued by enqueue and delivered by unlock, see there
+	events   []watchedEvent[KT, VT]
+	delivery chan struct{}
 }
 
 // Set sets a key=value pair in the map.
@@ -15,7 +22,8 @@ func (m *Dictionary[KT, VT]) Set(k KT, v VT) {
 		m.m = make(map[KT]VT)
 	}
 	m.m[k] = v
-	m.lock.Unlock()
+	m.enqueue(DictSet, k, v)
+	m.unlock()
 }
 
 func (m *Dictionary[KT, VT]) Get(k KT) VT {
@@ -42,32 +50,34 @@ func (m *Dictionary[KT, VT]) Contains(k KT) bool {
 // Remove deletes the key from the dictionary.
 func (m *Dictionary[KT, VT]) Remove(k KT) bool {
 	m.lock.Lock()
-	defer m.lock.Unlock()
-	_, ok := m.m[k]
+	defer m.unlock()
+	v, ok := m.m[k]
 	if !ok {
 		return false
 	}
 	delete(m.m, k)
+	m.enqueue(DictRemove, k, v)
 	return true
 }
 
 // Pop removes and returns the value of the key.
 func (m *Dictionary[KT, VT]) Pop(k KT) (VT, bool) {
 	m.lock.Lock()
-	defer m.lock.Unlock()
+	defer m.unlock()
 	v, ok := m.m[k]
 	if !ok {
 		var zv VT
 		return zv, false
 	}
 	delete(m.m, k)
+	m.enqueue(DictRemove, k, v)
 	return v, true
 }
 
 func (m *Dictionary[KT, VT]) Clear() {
 	m.lock.Lock()
-	m.m = make(map[KT]VT)
-	m.lock.Unlock()
+	m.clearLocked()
+	m.unlock()
 }
 
 // Each calls the given function for each key=value pair in the map.
@@ -91,6 +101,36 @@ func (m *Dictionary[KT, VT]) Each(fn func(KT, VT) bool) {
 	}
 }
 
+// All returns an iterator over the key=value pairs of the map, in no
+// particular order; a map has no order, so there is no Backward (see
+// SortedDictionary). Like Each, it iterates a copy of the map taken when
+// the iteration starts, so the loop may modify the dictionary.
+func (m *Dictionary[KT, VT]) All() iter.Seq2[KT, VT] {
+	return func(yield func(KT, VT) bool) {
+		m.Each(yield)
+	}
+}
+
+// Keys returns an iterator over the keys of the map, in no particular
+// order. Like All, it iterates a copy of the map.
+func (m *Dictionary[KT, VT]) Keys() iter.Seq[KT] {
+	return func(yield func(KT) bool) {
+		m.Each(func(k KT, _ VT) bool {
+			return yield(k)
+		})
+	}
+}
+
+// Values returns an iterator over the values of the map, in no particular
+// order. Like All, it iterates a copy of the map.
+func (m *Dictionary[KT, VT]) Values() iter.Seq[VT] {
+	return func(yield func(VT) bool) {
+		m.Each(func(_ KT, v VT) bool {
+			return yield(v)
+		})
+	}
+}
+
 // Map returns a map copy of the dictionary.
 func (m *Dictionary[KT, VT]) Map() map[KT]VT {
 	m.lock.RLock()
@@ -104,3 +144,96 @@ func (m *Dictionary[KT, VT]) Map() map[KT]VT {
 	}
 	return m2
 }
+
+// GetOrSet returns the value of the key if it is set. Otherwise it sets the
+// key to v and returns v. loaded is true if the value was already set.
+func (m *Dictionary[KT, VT]) GetOrSet(k KT, v VT) (actual VT, loaded bool) {
+	m.lock.Lock()
+	defer m.unlock()
+	if old, ok := m.m[k]; ok {
+		return old, true
+	}
+	if m.m == nil {
+		m.m = make(map[KT]VT)
+	}
+	m.m[k] = v
+	m.enqueue(DictSet, k, v)
+	return v, false
+}
+
+// SetIfAbsent sets the key to v if it isn't set, and returns true if it
+// did.
+func (m *Dictionary[KT, VT]) SetIfAbsent(k KT, v VT) bool {
+	_, loaded := m.GetOrSet(k, v)
+	return !loaded
+}
+
+// CompareAndSwap sets the key to new if its value is equal to old, and
+// returns true if it did. Like sync.Map, it panics if the values are not
+// of a comparable type.
+func (m *Dictionary[KT, VT]) CompareAndSwap(k KT, old, new VT) bool {
+	m.lock.Lock()
+	defer m.unlock()
+	v, ok := m.m[k]
+	if !ok || any(v) != any(old) {
+		return false
+	}
+	m.m[k] = new
+	m.enqueue(DictSet, k, new)
+	return true
+}
+
+// CompareAndDelete deletes the key if its value is equal to old, and
+// returns true if it did. Like sync.Map, it panics if the values are not
+// of a comparable type.
+func (m *Dictionary[KT, VT]) CompareAndDelete(k KT, old VT) bool {
+	m.lock.Lock()
+	defer m.unlock()
+	v, ok := m.m[k]
+	if !ok || any(v) != any(old) {
+		return false
+	}
+	delete(m.m, k)
+	m.enqueue(DictRemove, k, v)
+	return true
+}
+
+// Update replaces the value of the key with the result of fn, if the key
+// is set. It returns true if the key was set. fn runs under the lock, so it
+// must not call other methods of the dictionary.
+func (m *Dictionary[KT, VT]) Update(k KT, fn func(v VT) VT) bool {
+	m.lock.Lock()
+	defer m.unlock()
+	v, ok := m.m[k]
+	if !ok {
+		return false
+	}
+	v = fn(v)
+	m.m[k] = v
+	m.enqueue(DictSet, k, v)
+	return true
+}
+
+// Compute calls fn with the value of the key and whether it is set. If fn
+// returns keep, the key is set to the returned value, otherwise the key is
+// deleted. Compute returns what fn returned. fn runs under the lock, so it
+// must not call other methods of the dictionary.
+func (m *Dictionary[KT, VT]) Compute(k KT, fn func(old VT, exists bool) (new VT, keep bool)) (VT, bool) {
+	m.lock.Lock()
+	defer m.unlock()
+	old, exists := m.m[k]
+	v, keep := fn(old, exists)
+	if !keep {
+		if exists {
+			delete(m.m, k)
+			m.enqueue(DictRemove, k, old)
+		}
+		return v, false
+	}
+	if m.m == nil {
+		m.m = make(map[KT]VT)
+	}
+	m.m[k] = v
+	m.enqueue(DictSet, k, v)
+	return v, true
+}
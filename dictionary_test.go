@@ -1,6 +1,9 @@
 package container_test
 
 import (
+	"maps"
+	"slices"
+	"sync"
 	"testing"
 
 	"github.com/gabstv/container"
@@ -52,3 +55,115 @@ func TestDictionary(t *testing.T) {
 	assert.Equal(t, 0.0, d3.Get(&k1))
 	assert.False(t, d3.Contains(&k1))
 }
+
+// atomicDictionary is implemented by Dictionary and ShardedDictionary.
+type atomicDictionary interface {
+	Get(k string) int
+	Contains(k string) bool
+	GetOrSet(k string, v int) (int, bool)
+	SetIfAbsent(k string, v int) bool
+	CompareAndSwap(k string, old, new int) bool
+	CompareAndDelete(k string, old int) bool
+	Update(k string, fn func(v int) int) bool
+	Compute(k string, fn func(old int, exists bool) (int, bool)) (int, bool)
+}
+
+func testAtomicOperations(t *testing.T, d atomicDictionary) {
+	v, loaded := d.GetOrSet("a", 1)
+	assert.False(t, loaded)
+	assert.Equal(t, 1, v)
+	v, loaded = d.GetOrSet("a", 2)
+	assert.True(t, loaded)
+	assert.Equal(t, 1, v)
+
+	assert.False(t, d.SetIfAbsent("a", 3))
+	assert.True(t, d.SetIfAbsent("b", 3))
+	assert.Equal(t, 3, d.Get("b"))
+
+	assert.False(t, d.CompareAndSwap("a", 5, 6))
+	assert.False(t, d.CompareAndSwap("x", 0, 6))
+	assert.False(t, d.Contains("x"))
+	assert.True(t, d.CompareAndSwap("a", 1, 6))
+	assert.Equal(t, 6, d.Get("a"))
+
+	assert.False(t, d.CompareAndDelete("a", 1))
+	assert.False(t, d.CompareAndDelete("x", 0))
+	assert.True(t, d.CompareAndDelete("a", 6))
+	assert.False(t, d.Contains("a"))
+
+	assert.False(t, d.Update("a", func(v int) int { return v + 1 }))
+	assert.False(t, d.Contains("a"))
+	assert.True(t, d.Update("b", func(v int) int { return v + 1 }))
+	assert.Equal(t, 4, d.Get("b"))
+
+	v, ok := d.Compute("c", func(old int, exists bool) (int, bool) {
+		assert.False(t, exists)
+		return old + 10, true
+	})
+	assert.True(t, ok)
+	assert.Equal(t, 10, v)
+	assert.Equal(t, 10, d.Get("c"))
+	_, ok = d.Compute("c", func(old int, exists bool) (int, bool) {
+		assert.True(t, exists)
+		assert.Equal(t, 10, old)
+		return 0, false
+	})
+	assert.False(t, ok)
+	assert.False(t, d.Contains("c"))
+}
+
+func testConcurrentCompute(t *testing.T, d atomicDictionary) {
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				d.Compute("n", func(old int, _ bool) (int, bool) {
+					return old + 1, true
+				})
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 8000, d.Get("n"))
+}
+
+func TestDictionaryAtomicOperations(t *testing.T) {
+	testAtomicOperations(t, &container.Dictionary[string, int]{})
+	testConcurrentCompute(t, &container.Dictionary[string, int]{})
+
+	var d container.Dictionary[string, []int]
+	d.Set("a", nil)
+	assert.Panics(t, func() {
+		d.CompareAndSwap("a", nil, []int{1})
+	})
+}
+
+func TestDictionaryIterators(t *testing.T) {
+	var d container.Dictionary[string, int]
+	assert.Empty(t, maps.Collect(d.All()))
+	d.Set("a", 1)
+	d.Set("b", 2)
+	d.Set("c", 3)
+	assert.Equal(t, map[string]int{"a": 1, "b": 2, "c": 3}, maps.Collect(d.All()))
+	assert.Equal(t, []string{"a", "b", "c"}, slices.Sorted(d.Keys()))
+	assert.Equal(t, []int{1, 2, 3}, slices.Sorted(d.Values()))
+
+	// the loop iterates a snapshot, so it can write to the dictionary
+	count := 0
+	for k, v := range d.All() {
+		d.Set(k+k, v)
+		d.Remove(k)
+		count++
+	}
+	assert.Equal(t, 3, count)
+	assert.Equal(t, []string{"aa", "bb", "cc"}, slices.Sorted(d.Keys()))
+
+	count = 0
+	for range d.Keys() {
+		count++
+		break
+	}
+	assert.Equal(t, 1, count)
+}
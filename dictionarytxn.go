@@ -0,0 +1,233 @@
+package container
+
+import (
+	"fmt"
+	"slices"
+)
+
+// DictTx is a transaction on a Dictionary or a ShardedDictionary. It reads
+// the dictionary as of the start of the transaction plus its own writes,
+// which are only applied if the transaction succeeds. It must not be used
+// after the function given to Txn returns.
+type DictTx[KT comparable, VT any] struct {
+	lookup func(k KT) (VT, bool)
+	// keys limits the transaction to some keys, see TxnKeys. It is nil
+	// when any key can be used.
+	keys    map[KT]struct{}
+	ops     []txOp[KT, VT]
+	index   map[KT]int
+	cleared bool
+	done    bool
+}
+
+type txOp[KT comparable, VT any] struct {
+	key     KT
+	value   VT
+	deleted bool
+}
+
+func newDictTx[KT comparable, VT any](lookup func(k KT) (VT, bool)) *DictTx[KT, VT] {
+	return &DictTx[KT, VT]{
+		lookup: lookup,
+		index:  make(map[KT]int),
+	}
+}
+
+func (tx *DictTx[KT, VT]) check() {
+	if tx.done {
+		panic("container: transaction used after Txn returned")
+	}
+}
+
+func (tx *DictTx[KT, VT]) checkKey(k KT) {
+	tx.check()
+	if _, ok := tx.keys[k]; tx.keys != nil && !ok {
+		panic(fmt.Sprintf("container: key %v not locked by the transaction", k))
+	}
+}
+
+func (tx *DictTx[KT, VT]) get(k KT) (VT, bool) {
+	tx.checkKey(k)
+	if i, ok := tx.index[k]; ok {
+		return tx.ops[i].value, !tx.ops[i].deleted
+	}
+	if tx.cleared {
+		var zv VT
+		return zv, false
+	}
+	return tx.lookup(k)
+}
+
+func (tx *DictTx[KT, VT]) write(op txOp[KT, VT]) {
+	if i, ok := tx.index[op.key]; ok {
+		tx.ops[i] = op
+		return
+	}
+	tx.index[op.key] = len(tx.ops)
+	tx.ops = append(tx.ops, op)
+}
+
+// Get returns the value of the key.
+func (tx *DictTx[KT, VT]) Get(k KT) VT {
+	v, _ := tx.get(k)
+	return v
+}
+
+// Contains returns true if the dictionary contains the key.
+func (tx *DictTx[KT, VT]) Contains(k KT) bool {
+	_, ok := tx.get(k)
+	return ok
+}
+
+// Set sets a key=value pair in the map.
+func (tx *DictTx[KT, VT]) Set(k KT, v VT) {
+	tx.checkKey(k)
+	tx.write(txOp[KT, VT]{key: k, value: v})
+}
+
+// Remove deletes the key from the dictionary.
+func (tx *DictTx[KT, VT]) Remove(k KT) bool {
+	_, ok := tx.Pop(k)
+	return ok
+}
+
+// Pop removes and returns the value of the key.
+func (tx *DictTx[KT, VT]) Pop(k KT) (VT, bool) {
+	v, ok := tx.get(k)
+	if ok {
+		tx.write(txOp[KT, VT]{key: k, deleted: true})
+	}
+	return v, ok
+}
+
+// Clear removes all keys. It panics in a transaction limited to some keys.
+func (tx *DictTx[KT, VT]) Clear() {
+	tx.check()
+	if tx.keys != nil {
+		panic("container: Clear in a transaction limited to some keys")
+	}
+	tx.cleared = true
+	tx.ops = tx.ops[:0]
+	clear(tx.index)
+}
+
+// Txn runs fn in a transaction. If fn returns nil, all its writes are
+// applied at once and watchers get their events. If fn returns an error,
+// the writes are dropped and Txn returns it. If fn panics, the writes are
+// dropped and the panic goes on, after the dictionary is unlocked.
+//
+// The dictionary is locked while fn runs, so fn must not use the
+// dictionary other than through tx.
+func (m *Dictionary[KT, VT]) Txn(fn func(tx *DictTx[KT, VT]) error) error {
+	m.lock.Lock()
+	defer m.unlock()
+	tx := newDictTx(func(k KT) (VT, bool) {
+		v, ok := m.m[k]
+		return v, ok
+	})
+	defer func() { tx.done = true }()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if tx.cleared {
+		m.clearLocked()
+	}
+	for _, op := range tx.ops {
+		m.applyLocked(op)
+	}
+	return nil
+}
+
+// Txn runs fn in a transaction, see Dictionary.Txn. All the shards are
+// locked while fn runs, so sharded transactions run one at a time and hold
+// off every other use of the dictionary. Use TxnKeys when the keys are
+// known up front.
+func (m *ShardedDictionary[KT, VT]) Txn(fn func(tx *DictTx[KT, VT]) error) error {
+	// always in the same order, so transactions don't deadlock
+	for i := range m.shards {
+		m.shards[i].lock.Lock()
+	}
+	defer func() {
+		for i := range m.shards {
+			m.shards[i].unlock()
+		}
+	}()
+	tx := newDictTx(func(k KT) (VT, bool) {
+		v, ok := m.shard(k).m[k]
+		return v, ok
+	})
+	defer func() { tx.done = true }()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if tx.cleared {
+		for i := range m.shards {
+			m.shards[i].clearLocked()
+		}
+	}
+	for _, op := range tx.ops {
+		m.shard(op.key).applyLocked(op)
+	}
+	return nil
+}
+
+// TxnKeys runs fn in a transaction limited to the given keys, see
+// Dictionary.Txn. Only the shards of those keys are locked, so transactions
+// on keys of other shards run concurrently. Using another key through tx,
+// or calling Clear, panics.
+func (m *ShardedDictionary[KT, VT]) TxnKeys(keys []KT, fn func(tx *DictTx[KT, VT]) error) error {
+	allowed := make(map[KT]struct{}, len(keys))
+	shards := make([]uint64, 0, len(keys))
+	for _, k := range keys {
+		allowed[k] = struct{}{}
+		shards = append(shards, m.hash(k)&m.mask)
+	}
+	// in index order like Txn, so transactions don't deadlock
+	slices.Sort(shards)
+	shards = slices.Compact(shards)
+	for _, i := range shards {
+		m.shards[i].lock.Lock()
+	}
+	defer func() {
+		for _, i := range shards {
+			m.shards[i].unlock()
+		}
+	}()
+	tx := newDictTx(func(k KT) (VT, bool) {
+		v, ok := m.shard(k).m[k]
+		return v, ok
+	})
+	tx.keys = allowed
+	defer func() { tx.done = true }()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	for _, op := range tx.ops {
+		m.shard(op.key).applyLocked(op)
+	}
+	return nil
+}
+
+// clearLocked removes all keys. The lock must be held.
+func (m *Dictionary[KT, VT]) clearLocked() {
+	m.m = make(map[KT]VT)
+	var zk KT
+	var zv VT
+	m.enqueue(DictClear, zk, zv)
+}
+
+// applyLocked applies a write of a transaction. The lock must be held.
+func (m *Dictionary[KT, VT]) applyLocked(op txOp[KT, VT]) {
+	if op.deleted {
+		if v, ok := m.m[op.key]; ok {
+			delete(m.m, op.key)
+			m.enqueue(DictRemove, op.key, v)
+		}
+		return
+	}
+	if m.m == nil {
+		m.m = make(map[KT]VT)
+	}
+	m.m[op.key] = op.value
+	m.enqueue(DictSet, op.key, op.value)
+}
@@ -0,0 +1,215 @@
+package container_test
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gabstv/container"
+	"github.com/stretchr/testify/assert"
+)
+
+// txnDictionary is implemented by Dictionary and ShardedDictionary.
+type txnDictionary interface {
+	Set(k string, v int)
+	Get(k string) int
+	Map() map[string]int
+	Txn(fn func(tx *container.DictTx[string, int]) error) error
+}
+
+func testTxn(t *testing.T, d txnDictionary) {
+	d.Set("a", 10)
+	d.Set("b", 5)
+
+	move := func(from, to string, amount int) error {
+		return d.Txn(func(tx *container.DictTx[string, int]) error {
+			if tx.Get(from) < amount {
+				return errors.New("insufficient funds")
+			}
+			tx.Set(from, tx.Get(from)-amount)
+			tx.Set(to, tx.Get(to)+amount)
+			return nil
+		})
+	}
+	assert.NoError(t, move("a", "b", 7))
+	assert.Equal(t, map[string]int{"a": 3, "b": 12}, d.Map())
+	assert.EqualError(t, move("a", "b", 7), "insufficient funds")
+	assert.Equal(t, map[string]int{"a": 3, "b": 12}, d.Map())
+
+	// writes are visible inside the transaction only
+	errAbort := errors.New("abort")
+	err := d.Txn(func(tx *container.DictTx[string, int]) error {
+		tx.Set("c", 1)
+		assert.True(t, tx.Contains("c"))
+		v, ok := tx.Pop("a")
+		assert.True(t, ok)
+		assert.Equal(t, 3, v)
+		assert.False(t, tx.Contains("a"))
+		assert.False(t, tx.Remove("a"))
+		tx.Clear()
+		assert.False(t, tx.Contains("b"))
+		tx.Set("d", 4)
+		return errAbort
+	})
+	assert.ErrorIs(t, err, errAbort)
+	assert.Equal(t, map[string]int{"a": 3, "b": 12}, d.Map())
+
+	assert.NoError(t, d.Txn(func(tx *container.DictTx[string, int]) error {
+		tx.Clear()
+		tx.Set("d", 4)
+		tx.Set("e", 5)
+		tx.Remove("e")
+		return nil
+	}))
+	assert.Equal(t, map[string]int{"d": 4}, d.Map())
+
+	// panics roll back too
+	assert.Panics(t, func() {
+		d.Txn(func(tx *container.DictTx[string, int]) error {
+			tx.Set("d", 40)
+			panic("boom")
+		})
+	})
+	assert.Equal(t, 4, d.Get("d"))
+
+	var leaked *container.DictTx[string, int]
+	d.Txn(func(tx *container.DictTx[string, int]) error {
+		leaked = tx
+		return nil
+	})
+	assert.Panics(t, func() { leaked.Set("x", 1) })
+}
+
+func testConcurrentTxn(t *testing.T, d txnDictionary) {
+	d.Set("a", 1000)
+	d.Set("b", 1000)
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				from, to := "a", "b"
+				if (g+i)%2 == 0 {
+					from, to = to, from
+				}
+				d.Txn(func(tx *container.DictTx[string, int]) error {
+					tx.Set(from, tx.Get(from)-1)
+					tx.Set(to, tx.Get(to)+1)
+					assert.Equal(t, 2000, tx.Get(from)+tx.Get(to))
+					return nil
+				})
+			}
+		}(g)
+	}
+	wg.Wait()
+	assert.Equal(t, 2000, d.Get("a")+d.Get("b"))
+}
+
+func TestDictionaryTxn(t *testing.T) {
+	testTxn(t, &container.Dictionary[string, int]{})
+	testConcurrentTxn(t, &container.Dictionary[string, int]{})
+}
+
+func TestShardedDictionaryTxn(t *testing.T) {
+	testTxn(t, container.NewShardedDictionary[string, int](4, nil))
+	testConcurrentTxn(t, container.NewShardedDictionary[string, int](4, nil))
+}
+
+func TestDictionaryTxnNotifies(t *testing.T) {
+	var d container.Dictionary[string, int]
+	d.Set("a", 1)
+	w := d.Watch(nil, 10, container.WatchDrop)
+	defer w.Close()
+
+	d.Txn(func(tx *container.DictTx[string, int]) error {
+		tx.Set("b", 2)
+		return errors.New("abort")
+	})
+	d.Txn(func(tx *container.DictTx[string, int]) error {
+		tx.Set("b", 2)
+		tx.Remove("a")
+		tx.Set("c", 3)
+		tx.Set("b", 20)
+		return nil
+	})
+	assert.Equal(t, strEvent{Kind: container.DictSet, Key: "b", Value: 20}, receive(t, w))
+	assert.Equal(t, strEvent{Kind: container.DictRemove, Key: "a", Value: 1}, receive(t, w))
+	assert.Equal(t, strEvent{Kind: container.DictSet, Key: "c", Value: 3}, receive(t, w))
+	assertNoEvent(t, w)
+	// the events go out after the dictionary is unlocked, so a watcher
+	// that blocks the writer can still read it
+	reads := make(chan int, 10)
+	ow := d.OnChange(nil, 1, container.WatchBlock, func(ev strEvent) {
+		reads <- d.Get("k0")
+	})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		d.Txn(func(tx *container.DictTx[string, int]) error {
+			for i := 0; i < 5; i++ {
+				tx.Set("k"+strconv.Itoa(i), i)
+			}
+			return nil
+		})
+		d.Clear()
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("transaction blocked by a reading watcher")
+	}
+	// five sets and a clear
+	for i := 0; i < 6; i++ {
+		<-reads
+	}
+	ow.Close()
+}
+
+// keysTxn runs the transactions of testConcurrentTxn with TxnKeys.
+type keysTxn struct {
+	*container.ShardedDictionary[string, int]
+}
+
+func (d keysTxn) Txn(fn func(tx *container.DictTx[string, int]) error) error {
+	return d.TxnKeys([]string{"a", "b"}, fn)
+}
+
+func TestShardedDictionaryTxnKeys(t *testing.T) {
+	// keys are spread over the shards by their first letter
+	byLetter := func(k string) uint64 {
+		return uint64(k[0])
+	}
+	testConcurrentTxn(t, keysTxn{container.NewShardedDictionary[string, int](4, byLetter)})
+
+	d := container.NewShardedDictionary[string, int](4, byLetter)
+	d.Set("a", 1)
+	d.Set("b", 2)
+	err := d.TxnKeys([]string{"a", "e"}, func(tx *container.DictTx[string, int]) error {
+		// "a" and "e" share a shard, "b" is in another one that isn't locked
+		d.Set("b", 3)
+		tx.Set("e", tx.Get("a")+d.Get("b"))
+		tx.Remove("a")
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"b": 3, "e": 4}, d.Map())
+
+	assert.Panics(t, func() {
+		d.TxnKeys([]string{"a"}, func(tx *container.DictTx[string, int]) error {
+			tx.Set("b", 10)
+			return nil
+		})
+	})
+	assert.Panics(t, func() {
+		d.TxnKeys([]string{"a"}, func(tx *container.DictTx[string, int]) error {
+			tx.Clear()
+			return nil
+		})
+	})
+	// the shards were unlocked and nothing was written
+	d.Set("a", 5)
+	assert.Equal(t, map[string]int{"a": 5, "b": 3, "e": 4}, d.Map())
+}
@@ -0,0 +1,308 @@
+package container
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// DictEventKind tells what changed in a Dictionary.
+type DictEventKind int
+
+const (
+	DictSet DictEventKind = iota + 1
+	DictRemove
+	DictClear
+)
+
+func (k DictEventKind) String() string {
+	switch k {
+	case DictSet:
+		return "set"
+	case DictRemove:
+		return "remove"
+	case DictClear:
+		return "clear"
+	}
+	return "unknown"
+}
+
+// DictEvent is a change of a Dictionary. Value is the new value for
+// DictSet and the removed value for DictRemove. DictClear events have no
+// key nor value.
+type DictEvent[KT comparable, VT any] struct {
+	Kind  DictEventKind
+	Key   KT
+	Value VT
+}
+
+// WatchPolicy tells what to do when a watcher doesn't keep up with the
+// events.
+type WatchPolicy int
+
+const (
+	// WatchDrop drops the events that don't fit in the buffer of the
+	// watcher. They are counted by Dropped.
+	WatchDrop WatchPolicy = iota
+	// WatchBlock makes the writers of the dictionary wait until the
+	// watcher has room for the event. Writers wait after releasing the
+	// lock, so the watcher may read the dictionary, but it must not write
+	// to it while it is behind, or it will deadlock.
+	WatchBlock
+	// WatchCoalesce keeps only the latest pending event of every key, and
+	// drops all the pending events on clear. Events are delivered in the
+	// order their keys first changed.
+	WatchCoalesce
+)
+
+// DictWatcher receives the events of a Dictionary on its channel C until
+// it is closed.
+type DictWatcher[KT comparable, VT any] struct {
+	// C delivers the events. It is closed by Close.
+	C <-chan DictEvent[KT, VT]
+
+	c       chan DictEvent[KT, VT]
+	dict    *Dictionary[KT, VT]
+	filter  func(k KT) bool
+	policy  WatchPolicy
+	closed  chan struct{}
+	once    sync.Once
+	dropped atomic.Uint64
+
+	// coalesce state, see pump
+	lock    sync.Mutex
+	pending []*pendingEvent[KT, VT]
+	index   map[KT]*pendingEvent[KT, VT]
+	seq     uint64
+	wake    chan struct{}
+	done    chan struct{}
+}
+
+type pendingEvent[KT comparable, VT any] struct {
+	ev  DictEvent[KT, VT]
+	seq uint64
+}
+
+// Watch returns a watcher for the events of the keys for which filter
+// returns true, or of all keys if filter is nil. Clear events are sent to
+// every watcher. buffer is the capacity of the channel of the watcher.
+func (m *Dictionary[KT, VT]) Watch(filter func(k KT) bool, buffer int, policy WatchPolicy) *DictWatcher[KT, VT] {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.watch(filter, buffer, policy)
+}
+
+// WatchKey returns a watcher for the events of one key.
+func (m *Dictionary[KT, VT]) WatchKey(k KT, buffer int, policy WatchPolicy) *DictWatcher[KT, VT] {
+	return m.Watch(func(key KT) bool {
+		return key == k
+	}, buffer, policy)
+}
+
+// OnChange calls fn with the events of the keys for which filter returns
+// true, or of all keys if filter is nil, until the returned watcher is
+// closed. fn runs on its own goroutine, one event at a time. The policy
+// applies when fn falls behind by more than buffer events.
+func (m *Dictionary[KT, VT]) OnChange(filter func(k KT) bool, buffer int, policy WatchPolicy, fn func(ev DictEvent[KT, VT])) *DictWatcher[KT, VT] {
+	w := m.Watch(filter, buffer, policy)
+	go func() {
+		for ev := range w.C {
+			fn(ev)
+		}
+	}()
+	return w
+}
+
+// WaitFor blocks until the key is set and returns its value, or returns
+// the error of the context if it is done first.
+func (m *Dictionary[KT, VT]) WaitFor(ctx context.Context, k KT) (VT, error) {
+	m.lock.Lock()
+	if v, ok := m.m[k]; ok {
+		m.lock.Unlock()
+		return v, nil
+	}
+	w := m.watch(func(key KT) bool {
+		return key == k
+	}, 1, WatchCoalesce)
+	m.lock.Unlock()
+	defer w.Close()
+
+	for {
+		select {
+		case ev := <-w.C:
+			if ev.Kind == DictSet {
+				return ev.Value, nil
+			}
+		case <-ctx.Done():
+			var zv VT
+			return zv, ctx.Err()
+		}
+	}
+}
+
+// watch registers a new watcher. The lock must be held.
+func (m *Dictionary[KT, VT]) watch(filter func(k KT) bool, buffer int, policy WatchPolicy) *DictWatcher[KT, VT] {
+	if buffer < 0 {
+		buffer = 0
+	}
+	w := &DictWatcher[KT, VT]{
+		c:      make(chan DictEvent[KT, VT], buffer),
+		dict:   m,
+		filter: filter,
+		policy: policy,
+		closed: make(chan struct{}),
+	}
+	w.C = w.c
+	if policy == WatchCoalesce {
+		w.index = make(map[KT]*pendingEvent[KT, VT])
+		w.wake = make(chan struct{}, 1)
+		w.done = make(chan struct{})
+		go w.pump()
+	}
+	m.watchers = append(m.watchers, w)
+	return w
+}
+
+type watchedEvent[KT comparable, VT any] struct {
+	w  *DictWatcher[KT, VT]
+	ev DictEvent[KT, VT]
+}
+
+// enqueue queues the event for the interested watchers. The write lock must
+// be held, and released with unlock to deliver the events.
+func (m *Dictionary[KT, VT]) enqueue(kind DictEventKind, k KT, v VT) {
+	for _, w := range m.watchers {
+		if kind == DictClear || w.filter == nil || w.filter(k) {
+			m.events = append(m.events, watchedEvent[KT, VT]{w, DictEvent[KT, VT]{kind, k, v}})
+		}
+	}
+}
+
+// unlock releases the write lock and delivers the events queued by enqueue,
+// so that watchers that block writers can still read the dictionary.
+// Deliveries are chained in the order of the changes: each one waits for
+// the previous one to finish before sending its events.
+func (m *Dictionary[KT, VT]) unlock() {
+	events := m.events
+	if len(events) == 0 {
+		m.lock.Unlock()
+		return
+	}
+	m.events = nil
+	prev, done := m.delivery, make(chan struct{})
+	m.delivery = done
+	m.lock.Unlock()
+
+	if prev != nil {
+		<-prev
+	}
+	for _, e := range events {
+		e.w.send(e.ev)
+	}
+	close(done)
+}
+
+func (w *DictWatcher[KT, VT]) send(ev DictEvent[KT, VT]) {
+	switch w.policy {
+	case WatchBlock:
+		select {
+		case w.c <- ev:
+		case <-w.closed:
+		}
+	case WatchCoalesce:
+		w.lock.Lock()
+		w.seq++
+		if ev.Kind == DictClear {
+			w.pending = append(w.pending[:0], &pendingEvent[KT, VT]{ev, w.seq})
+			clear(w.index)
+		} else if p, ok := w.index[ev.Key]; ok {
+			p.ev, p.seq = ev, w.seq
+		} else {
+			p := &pendingEvent[KT, VT]{ev, w.seq}
+			w.index[ev.Key] = p
+			w.pending = append(w.pending, p)
+		}
+		w.lock.Unlock()
+		select {
+		case w.wake <- struct{}{}:
+		default:
+		}
+	default:
+		select {
+		case w.c <- ev:
+		default:
+			w.dropped.Add(1)
+		}
+	}
+}
+
+// pump moves the pending events of a coalescing watcher to its channel.
+func (w *DictWatcher[KT, VT]) pump() {
+	defer close(w.done)
+	for {
+		w.lock.Lock()
+		if len(w.pending) == 0 {
+			w.lock.Unlock()
+			select {
+			case <-w.wake:
+				continue
+			case <-w.closed:
+				return
+			}
+		}
+		p := w.pending[0]
+		ev, seq := p.ev, p.seq
+		w.lock.Unlock()
+
+		select {
+		case w.c <- ev:
+		case <-w.wake:
+			// the pending events changed, ev may be stale
+			continue
+		case <-w.closed:
+			return
+		}
+		// unless it was replaced by a newer event while being sent
+		w.lock.Lock()
+		if len(w.pending) > 0 && w.pending[0] == p && p.seq == seq {
+			w.pending[0] = nil
+			w.pending = w.pending[1:]
+			if w.index[ev.Key] == p {
+				delete(w.index, ev.Key)
+			}
+		}
+		w.lock.Unlock()
+	}
+}
+
+// Dropped returns how many events were dropped because the watcher was
+// behind. It is always zero unless the policy is WatchDrop.
+func (w *DictWatcher[KT, VT]) Dropped() uint64 {
+	return w.dropped.Load()
+}
+
+// Close stops the watcher and closes its channel. Events still in the
+// channel can be received after Close.
+func (w *DictWatcher[KT, VT]) Close() {
+	w.once.Do(func() {
+		close(w.closed)
+		m := w.dict
+		m.lock.Lock()
+		for i, other := range m.watchers {
+			if other == w {
+				m.watchers = append(m.watchers[:i], m.watchers[i+1:]...)
+				break
+			}
+		}
+		delivery := m.delivery
+		m.lock.Unlock()
+		// wait for the events queued before the watcher was removed
+		if delivery != nil {
+			<-delivery
+		}
+		if w.done != nil {
+			<-w.done
+		}
+		close(w.c)
+	})
+}
@@ -0,0 +1,224 @@
+package container_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gabstv/container"
+	"github.com/stretchr/testify/assert"
+)
+
+type strEvent = container.DictEvent[string, int]
+
+func receive(t *testing.T, w *container.DictWatcher[string, int]) strEvent {
+	t.Helper()
+	select {
+	case ev := <-w.C:
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event received")
+	}
+	return strEvent{}
+}
+
+func assertNoEvent(t *testing.T, w *container.DictWatcher[string, int]) {
+	t.Helper()
+	select {
+	case ev := <-w.C:
+		t.Fatalf("unexpected event %+v", ev)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func TestDictionaryWatch(t *testing.T) {
+	var d container.Dictionary[string, int]
+	all := d.Watch(nil, 16, container.WatchDrop)
+	one := d.WatchKey("a", 16, container.WatchBlock)
+	prefixed := d.Watch(func(k string) bool { return k[0] == 'x' }, 16, container.WatchDrop)
+
+	d.Set("a", 1)
+	d.Set("b", 2)
+	d.Set("xy", 3)
+	d.Remove("a")
+	d.Remove("missing")
+	d.Update("b", func(v int) int { return v * 10 })
+	d.Compute("xy", func(old int, exists bool) (int, bool) { return 0, false })
+	d.Clear()
+
+	assert.Equal(t, strEvent{Kind: container.DictSet, Key: "a", Value: 1}, receive(t, all))
+	assert.Equal(t, strEvent{Kind: container.DictSet, Key: "b", Value: 2}, receive(t, all))
+	assert.Equal(t, strEvent{Kind: container.DictSet, Key: "xy", Value: 3}, receive(t, all))
+	assert.Equal(t, strEvent{Kind: container.DictRemove, Key: "a", Value: 1}, receive(t, all))
+	assert.Equal(t, strEvent{Kind: container.DictSet, Key: "b", Value: 20}, receive(t, all))
+	assert.Equal(t, strEvent{Kind: container.DictRemove, Key: "xy", Value: 3}, receive(t, all))
+	assert.Equal(t, strEvent{Kind: container.DictClear}, receive(t, all))
+	assertNoEvent(t, all)
+
+	assert.Equal(t, strEvent{Kind: container.DictSet, Key: "a", Value: 1}, receive(t, one))
+	assert.Equal(t, strEvent{Kind: container.DictRemove, Key: "a", Value: 1}, receive(t, one))
+	assert.Equal(t, strEvent{Kind: container.DictClear}, receive(t, one))
+	assertNoEvent(t, one)
+
+	assert.Equal(t, container.DictSet, receive(t, prefixed).Kind)
+	assert.Equal(t, container.DictRemove, receive(t, prefixed).Kind)
+	assert.Equal(t, container.DictClear, receive(t, prefixed).Kind)
+	assertNoEvent(t, prefixed)
+
+	all.Close()
+	all.Close()
+	_, ok := <-all.C
+	assert.False(t, ok)
+	d.Set("a", 5)
+	assert.Equal(t, 5, receive(t, one).Value)
+	one.Close()
+	prefixed.Close()
+}
+
+func TestDictionaryWatchDrop(t *testing.T) {
+	var d container.Dictionary[string, int]
+	w := d.Watch(nil, 2, container.WatchDrop)
+	defer w.Close()
+	for i := 0; i < 5; i++ {
+		d.Set("a", i)
+	}
+	assert.Equal(t, uint64(3), w.Dropped())
+	assert.Equal(t, 0, receive(t, w).Value)
+	assert.Equal(t, 1, receive(t, w).Value)
+	assertNoEvent(t, w)
+}
+
+func TestDictionaryWatchBlock(t *testing.T) {
+	var d container.Dictionary[string, int]
+	w := d.Watch(nil, 0, container.WatchBlock)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 3; i++ {
+			d.Set("a", i)
+		}
+	}()
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, i, receive(t, w).Value)
+	}
+	<-done
+
+	// closing releases blocked writers; the value is set before the
+	// writer delivers its event
+	blocked := make(chan struct{})
+	go func() {
+		defer close(blocked)
+		d.Set("a", 10)
+	}()
+	waitFor(t, func() bool { return d.Get("a") == 10 })
+	w.Close()
+	select {
+	case <-blocked:
+	case <-time.After(5 * time.Second):
+		t.Fatal("writer still blocked after Close")
+	}
+	d.Set("a", 11)
+	assert.Equal(t, 11, d.Get("a"))
+}
+
+func TestDictionaryWatchCoalesce(t *testing.T) {
+	var d container.Dictionary[string, int]
+	w := d.Watch(nil, 0, container.WatchCoalesce)
+	defer w.Close()
+
+	// the pump may hold on to the first event until it is received
+	d.Set("a", 0)
+	for i := 1; i <= 5; i++ {
+		d.Set("a", i)
+		d.Set("b", i)
+	}
+	d.Remove("c")
+	d.Set("c", 1)
+	d.Remove("c")
+
+	got := []strEvent{}
+	for len(got) == 0 || got[len(got)-1].Key != "c" {
+		got = append(got, receive(t, w))
+	}
+	// "a" may have been delivered before or after coalescing
+	last := got[len(got)-3:]
+	assert.Equal(t, []strEvent{
+		{Kind: container.DictSet, Key: "a", Value: 5},
+		{Kind: container.DictSet, Key: "b", Value: 5},
+		{Kind: container.DictRemove, Key: "c", Value: 1},
+	}, last)
+	assertNoEvent(t, w)
+
+	d.Set("a", 1)
+	d.Set("b", 1)
+	d.Clear()
+	ev := receive(t, w)
+	for ev.Kind != container.DictClear {
+		ev = receive(t, w)
+	}
+	assertNoEvent(t, w)
+}
+
+func TestDictionaryOnChange(t *testing.T) {
+	var d container.Dictionary[string, int]
+	events := make(chan strEvent, 10)
+	w := d.OnChange(nil, 10, container.WatchBlock, func(ev strEvent) {
+		// callbacks run on their own goroutine and can read the dictionary
+		d.Contains(ev.Key)
+		events <- ev
+	})
+	d.Set("a", 1)
+	d.Remove("a")
+	assert.Equal(t, strEvent{Kind: container.DictSet, Key: "a", Value: 1}, <-events)
+	assert.Equal(t, strEvent{Kind: container.DictRemove, Key: "a", Value: 1}, <-events)
+	w.Close()
+}
+
+func TestDictionaryOnChangeReadsWhileBehind(t *testing.T) {
+	var d container.Dictionary[string, int]
+	sums := make(chan int, 100)
+	w := d.OnChange(nil, 1, container.WatchBlock, func(ev strEvent) {
+		// reading while the writer waits for room in the buffer
+		sums <- d.Get("a") + d.Get("b")
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 50; i++ {
+			d.Set("a", i)
+			d.Set("b", i)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("writer blocked by a reading watcher")
+	}
+	for i := 0; i < 100; i++ {
+		<-sums
+	}
+	w.Close()
+}
+
+func TestDictionaryWaitFor(t *testing.T) {
+	var d container.Dictionary[string, int]
+	d.Set("ready", 1)
+	v, err := d.WaitFor(context.Background(), "ready")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, v)
+
+	// the key may be set before or after WaitFor starts watching
+	go func() {
+		d.Set("other", 2)
+		d.Set("later", 3)
+	}()
+	v, err = d.WaitFor(context.Background(), "later")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, v)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = d.WaitFor(ctx, "never")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,518 @@
+package container
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+var (
+	ErrDictionaryClosed   = errors.New("dictionary is closed")
+	ErrDictionaryLocked   = errors.New("dictionary is opened by someone else")
+	ErrDictionaryFailed   = errors.New("dictionary log failed")
+	ErrCorruptDurableData = errors.New("corrupt durable dictionary data")
+)
+
+const (
+	durableLogName      = "wal.log"
+	durableSnapshotName = "snapshot"
+	durableLockName     = "lock"
+
+	// DefaultCompactEvery is the default number of log records that
+	// trigger a compaction.
+	DefaultCompactEvery = 4096
+)
+
+const (
+	walSet byte = iota + 1
+	walDelete
+	walClear
+)
+
+// A record is a header followed by its payload. The header holds the
+// length of the payload, a checksum of the length and a checksum of the
+// payload, all little endian uint32.
+const walHeaderSize = 12
+
+var walTable = crc32.MakeTable(crc32.Castagnoli)
+
+// DurableOptions configures a DurableDictionary.
+type DurableOptions[KT comparable, VT any] struct {
+	// KeyCodec and ValueCodec encode the keys and values, GobCodec if nil.
+	KeyCodec   Codec[KT]
+	ValueCodec Codec[VT]
+	// CompactEvery is how many records the log holds before it is
+	// compacted into the snapshot. Zero means DefaultCompactEvery, and a
+	// negative value means the log is only compacted by Compact.
+	CompactEvery int
+	// OnCompactError is called with the error of a compaction started by
+	// a write. The write itself succeeded; the compaction is tried again
+	// after CompactEvery more records.
+	OnCompactError func(err error)
+	// Sync makes every write wait until the log is flushed to the disk.
+	// Without it, writes survive the process crashing but may be lost if
+	// the system does.
+	Sync bool
+}
+
+// DurableDictionary is a thread-safe dictionary whose contents survive
+// restarts. Every write is appended to a log in its directory before it is
+// applied; once the log grows long enough, the contents are written to a
+// snapshot and the log starts over.
+//
+// Opening the directory again replays the snapshot and the log. A record
+// torn by a crash at the end of the log is dropped, along with the write it
+// held, which was never acknowledged. A damaged record anywhere else is an
+// error wrapping ErrCorruptDurableData, since acknowledged writes follow it.
+// The length of every record has its own checksum, so a damaged length is
+// never mistaken for a torn tail.
+//
+// Writes return an error if they can't be logged, and are then not
+// applied: the partial record is cut off the log. If that fails too, the
+// log can't be trusted anymore and all later writes fail with an error
+// wrapping ErrDictionaryFailed; reopening the dictionary recovers the
+// acknowledged writes. If the compaction that may follow a write fails,
+// the write still succeeds and the error goes to OnCompactError.
+//
+// The directory is locked while the dictionary is open, opening it again
+// fails with ErrDictionaryLocked.
+type DurableDictionary[KT comparable, VT any] struct {
+	// lock serializes the writes, so the log and dict apply them in the
+	// same order. Reads only use the lock of dict.
+	lock    sync.Mutex
+	dict    Dictionary[KT, VT]
+	dir     string
+	dirLock *os.File
+	log     *os.File
+	// offset is the end of the last complete record of the log
+	offset  int64
+	failed  error
+	records int
+	// compactAt is the number of records that triggers a compaction
+	compactAt      int
+	keys           Codec[KT]
+	values         Codec[VT]
+	compactEvery   int
+	onCompactError func(err error)
+	sync           bool
+}
+
+// OpenDurableDictionary opens the dictionary stored in dir, creating the
+// directory if needed, and recovers its contents. It fails with
+// ErrDictionaryLocked if the dictionary is already open.
+func OpenDurableDictionary[KT comparable, VT any](dir string, opts DurableOptions[KT, VT]) (_ *DurableDictionary[KT, VT], err error) {
+	m := &DurableDictionary[KT, VT]{
+		dir:            dir,
+		keys:           opts.KeyCodec,
+		values:         opts.ValueCodec,
+		compactEvery:   opts.CompactEvery,
+		onCompactError: opts.OnCompactError,
+		sync:           opts.Sync,
+	}
+	if m.keys == nil {
+		m.keys = GobCodec[KT]()
+	}
+	if m.values == nil {
+		m.values = GobCodec[VT]()
+	}
+	if m.compactEvery == 0 {
+		m.compactEvery = DefaultCompactEvery
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	if m.dirLock, err = lockDir(dir); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			unlockDir(m.dirLock)
+		}
+	}()
+	// left over by a compaction that didn't finish
+	os.Remove(filepath.Join(dir, durableSnapshotName+".tmp"))
+
+	snapshot, err := os.Open(filepath.Join(dir, durableSnapshotName))
+	switch {
+	case err == nil:
+		_, torn, err := m.replay(snapshot)
+		snapshot.Close()
+		if err == nil && torn {
+			err = fmt.Errorf("%w: truncated snapshot", ErrCorruptDurableData)
+		}
+		if err != nil {
+			return nil, err
+		}
+		m.records = 0
+	case !os.IsNotExist(err):
+		return nil, err
+	}
+
+	m.log, err = os.OpenFile(filepath.Join(dir, durableLogName), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	end, torn, err := m.replay(m.log)
+	if err == nil && torn {
+		err = m.log.Truncate(end)
+	}
+	if err == nil {
+		_, err = m.log.Seek(end, io.SeekStart)
+	}
+	if err != nil {
+		m.log.Close()
+		return nil, err
+	}
+	m.offset = end
+	m.compactAt = m.compactEvery
+	return m, nil
+}
+
+// replay applies the records of r to dict. It returns the offset after the
+// last complete record, and whether there were bytes after it. Only the last
+// record may be torn; a damaged record followed by more is an error.
+//
+// A crash may also leave zeros at the end of the log, when the file was
+// extended but the data never reached the disk. A damaged record followed
+// by nothing but zeros is torn as well. Otherwise, a complete header with
+// a bad length checksum is an error: without the length there is no
+// telling whether the record is the last one.
+func (m *DurableDictionary[KT, VT]) replay(r io.Reader) (end int64, torn bool, err error) {
+	var header [walHeaderSize]byte
+	for {
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return end, err != io.EOF, nil
+		}
+		if crc32.Checksum(header[:4], walTable) != binary.LittleEndian.Uint32(header[4:8]) {
+			zeros, err := zeroTail(r)
+			if err != nil {
+				return end, false, err
+			}
+			if !zeros || !isZero(header[:]) {
+				return end, false, fmt.Errorf("%w: bad record length at offset %d", ErrCorruptDurableData, end)
+			}
+			return end, true, nil
+		}
+		// don't trust the length with a large allocation before reading
+		size := int(binary.LittleEndian.Uint32(header[:4]))
+		payload, err := io.ReadAll(io.LimitReader(r, int64(size)))
+		if err != nil {
+			return end, false, err
+		}
+		if len(payload) < size {
+			return end, true, nil
+		}
+		if crc32.Checksum(payload, walTable) != binary.LittleEndian.Uint32(header[8:]) {
+			zeros, err := zeroTail(r)
+			if err != nil {
+				return end, false, err
+			}
+			if !zeros {
+				return end, false, fmt.Errorf("%w: bad checksum at offset %d", ErrCorruptDurableData, end)
+			}
+			return end, true, nil
+		}
+		if err := m.apply(payload); err != nil {
+			return end, false, err
+		}
+		end += int64(len(header) + len(payload))
+		m.records++
+	}
+}
+
+// zeroTail reads the rest of r and reports whether it only holds zeros.
+func zeroTail(r io.Reader) (bool, error) {
+	buf := make([]byte, 4096)
+	for {
+		n, err := r.Read(buf)
+		if !isZero(buf[:n]) {
+			return false, nil
+		}
+		if err == io.EOF {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+}
+
+func isZero(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// apply decodes a record and applies it to dict.
+func (m *DurableDictionary[KT, VT]) apply(payload []byte) error {
+	if len(payload) == 0 {
+		return fmt.Errorf("%w: empty record", ErrCorruptDurableData)
+	}
+	op, payload := payload[0], payload[1:]
+	if op == walClear {
+		m.dict.Clear()
+		return nil
+	}
+	n, size := binary.Uvarint(payload)
+	if size <= 0 || n > uint64(len(payload)-size) {
+		return fmt.Errorf("%w: bad key length", ErrCorruptDurableData)
+	}
+	k, err := m.keys.Decode(payload[size : size+int(n)])
+	if err != nil {
+		return err
+	}
+	switch op {
+	case walSet:
+		v, err := m.values.Decode(payload[size+int(n):])
+		if err != nil {
+			return err
+		}
+		m.dict.Set(k, v)
+	case walDelete:
+		m.dict.Remove(k)
+	default:
+		return fmt.Errorf("%w: unknown record type %d", ErrCorruptDurableData, op)
+	}
+	return nil
+}
+
+// record encodes a record with its header.
+func (m *DurableDictionary[KT, VT]) record(op byte, k KT, v VT) ([]byte, error) {
+	buf := make([]byte, walHeaderSize, 64)
+	buf = append(buf, op)
+	if op != walClear {
+		key, err := m.keys.Encode(k)
+		if err != nil {
+			return nil, err
+		}
+		buf = binary.AppendUvarint(buf, uint64(len(key)))
+		buf = append(buf, key...)
+	}
+	if op == walSet {
+		value, err := m.values.Encode(v)
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, value...)
+	}
+	binary.LittleEndian.PutUint32(buf[:4], uint32(len(buf)-walHeaderSize))
+	binary.LittleEndian.PutUint32(buf[4:8], crc32.Checksum(buf[:4], walTable))
+	binary.LittleEndian.PutUint32(buf[8:12], crc32.Checksum(buf[walHeaderSize:], walTable))
+	return buf, nil
+}
+
+// write appends a record to the log, compacting it if it is long enough.
+// The lock must be held.
+func (m *DurableDictionary[KT, VT]) write(op byte, k KT, v VT) error {
+	if m.log == nil {
+		return ErrDictionaryClosed
+	}
+	if m.failed != nil {
+		return m.failed
+	}
+	rec, err := m.record(op, k, v)
+	if err != nil {
+		return err
+	}
+	_, err = m.log.Write(rec)
+	if err == nil && m.sync {
+		err = m.log.Sync()
+	}
+	if err != nil {
+		// later records must not follow a torn one, replay would stop there
+		m.truncate(m.offset)
+		return err
+	}
+	m.offset += int64(len(rec))
+	m.records++
+	return nil
+}
+
+// truncate cuts the log at offset and moves there. If it can't, later
+// writes fail. The lock must be held.
+func (m *DurableDictionary[KT, VT]) truncate(offset int64) error {
+	err := m.log.Truncate(offset)
+	if err == nil {
+		_, err = m.log.Seek(offset, io.SeekStart)
+	}
+	if err != nil {
+		m.failed = fmt.Errorf("%w: %w", ErrDictionaryFailed, err)
+		return err
+	}
+	m.offset = offset
+	return nil
+}
+
+// afterWrite compacts the log if it is long enough. If that fails, the
+// next try waits for another compactEvery records. The lock must be held.
+func (m *DurableDictionary[KT, VT]) afterWrite() {
+	if m.compactEvery <= 0 || m.records < m.compactAt {
+		return
+	}
+	if err := m.compact(); err != nil {
+		m.compactAt = m.records + m.compactEvery
+		if m.onCompactError != nil {
+			m.onCompactError(err)
+		}
+	}
+}
+
+// Set sets a key=value pair in the map.
+func (m *DurableDictionary[KT, VT]) Set(k KT, v VT) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if err := m.write(walSet, k, v); err != nil {
+		return err
+	}
+	m.dict.Set(k, v)
+	m.afterWrite()
+	return nil
+}
+
+// Get returns the value of the key.
+func (m *DurableDictionary[KT, VT]) Get(k KT) VT {
+	return m.dict.Get(k)
+}
+
+// Contains returns true if the dictionary contains the key.
+func (m *DurableDictionary[KT, VT]) Contains(k KT) bool {
+	return m.dict.Contains(k)
+}
+
+// Remove deletes the key from the dictionary.
+func (m *DurableDictionary[KT, VT]) Remove(k KT) (bool, error) {
+	_, ok, err := m.Pop(k)
+	return ok, err
+}
+
+// Pop removes and returns the value of the key.
+func (m *DurableDictionary[KT, VT]) Pop(k KT) (VT, bool, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	var zv VT
+	if !m.dict.Contains(k) {
+		return zv, false, nil
+	}
+	if err := m.write(walDelete, k, zv); err != nil {
+		return zv, false, err
+	}
+	v, ok := m.dict.Pop(k)
+	m.afterWrite()
+	return v, ok, nil
+}
+
+// Clear removes all keys.
+func (m *DurableDictionary[KT, VT]) Clear() error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	var zk KT
+	var zv VT
+	if err := m.write(walClear, zk, zv); err != nil {
+		return err
+	}
+	m.dict.Clear()
+	m.afterWrite()
+	return nil
+}
+
+// Each calls the given function for each key=value pair in the map.
+// It creates a copy of the map to iterate, so setting a key inside
+// the loop will not affect the iteration.
+func (m *DurableDictionary[KT, VT]) Each(fn func(KT, VT) bool) {
+	m.dict.Each(fn)
+}
+
+// Map returns a map copy of the dictionary.
+func (m *DurableDictionary[KT, VT]) Map() map[KT]VT {
+	return m.dict.Map()
+}
+
+// Compact writes the contents to a new snapshot and empties the log.
+func (m *DurableDictionary[KT, VT]) Compact() error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.log == nil {
+		return ErrDictionaryClosed
+	}
+	return m.compact()
+}
+
+// compact replaces the snapshot and empties the log. If it crashes after
+// the snapshot was replaced, the log is replayed over a snapshot that
+// already has its writes; that is harmless, since replaying the same
+// writes again leads to the same contents. The lock must be held.
+func (m *DurableDictionary[KT, VT]) compact() error {
+	tmp := filepath.Join(m.dir, durableSnapshotName+".tmp")
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	var werr error
+	m.dict.Each(func(k KT, v VT) bool {
+		var rec []byte
+		if rec, werr = m.record(walSet, k, v); werr == nil {
+			_, werr = f.Write(rec)
+		}
+		return werr == nil
+	})
+	if werr == nil {
+		werr = f.Sync()
+	}
+	if err := f.Close(); werr == nil {
+		werr = err
+	}
+	if werr == nil {
+		werr = os.Rename(tmp, filepath.Join(m.dir, durableSnapshotName))
+	}
+	if werr != nil {
+		os.Remove(tmp)
+		return werr
+	}
+	if err := syncDir(m.dir); err != nil {
+		return err
+	}
+
+	if err := m.truncate(0); err != nil {
+		return err
+	}
+	m.records = 0
+	m.compactAt = m.compactEvery
+	return m.log.Sync()
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// Close flushes and closes the log and unlocks the directory. The
+// dictionary can still be read afterwards, but writes fail with
+// ErrDictionaryClosed.
+func (m *DurableDictionary[KT, VT]) Close() error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.log == nil {
+		return nil
+	}
+	err := m.log.Sync()
+	if cerr := m.log.Close(); err == nil {
+		err = cerr
+	}
+	if uerr := unlockDir(m.dirLock); err == nil {
+		err = uerr
+	}
+	m.log = nil
+	return err
+}
@@ -0,0 +1,313 @@
+package container_test
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/gabstv/container"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDurableDictionary(t *testing.T) {
+	dir := t.TempDir()
+	opts := container.DurableOptions[string, int]{Sync: true}
+	d, err := container.OpenDurableDictionary(dir, opts)
+	require.NoError(t, err)
+	// only one can have the directory open
+	_, err = container.OpenDurableDictionary(dir, opts)
+	assert.ErrorIs(t, err, container.ErrDictionaryLocked)
+	assert.NoError(t, d.Set("a", 1))
+	assert.NoError(t, d.Set("b", 2))
+	assert.NoError(t, d.Set("c", 3))
+	ok, err := d.Remove("b")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = d.Remove("b")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	v, ok, err := d.Pop("c")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 3, v)
+	assert.NoError(t, d.Set("a", 10))
+	assert.Equal(t, 10, d.Get("a"))
+	assert.NoError(t, d.Close())
+	assert.ErrorIs(t, d.Set("x", 1), container.ErrDictionaryClosed)
+	assert.NoError(t, d.Close())
+
+	d, err = container.OpenDurableDictionary(dir, opts)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"a": 10}, d.Map())
+	assert.NoError(t, d.Clear())
+	assert.NoError(t, d.Set("z", 26))
+	assert.NoError(t, d.Close())
+
+	d, err = container.OpenDurableDictionary(dir, opts)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"z": 26}, d.Map())
+	assert.True(t, d.Contains("z"))
+	assert.NoError(t, d.Close())
+}
+
+func TestDurableDictionaryCompaction(t *testing.T) {
+	dir := t.TempDir()
+	opts := container.DurableOptions[int, string]{
+		KeyCodec:     container.JSONCodec[int](),
+		ValueCodec:   container.JSONCodec[string](),
+		CompactEvery: 10,
+	}
+	d, err := container.OpenDurableDictionary(dir, opts)
+	require.NoError(t, err)
+	want := map[int]string{}
+	for i := 0; i < 25; i++ {
+		assert.NoError(t, d.Set(i%7, string(rune('a'+i))))
+		want[i%7] = string(rune('a' + i))
+	}
+	// 25 writes, compacted twice: 5 records left in the log
+	log, err := os.ReadFile(filepath.Join(dir, "wal.log"))
+	require.NoError(t, err)
+	assert.Equal(t, 5, countRecords(log))
+	assert.FileExists(t, filepath.Join(dir, "snapshot"))
+	assert.NoError(t, d.Close())
+
+	d, err = container.OpenDurableDictionary(dir, opts)
+	require.NoError(t, err)
+	assert.Equal(t, want, d.Map())
+
+	assert.NoError(t, d.Compact())
+	log, err = os.ReadFile(filepath.Join(dir, "wal.log"))
+	require.NoError(t, err)
+	assert.Empty(t, log)
+	assert.NoError(t, d.Close())
+	assert.ErrorIs(t, d.Compact(), container.ErrDictionaryClosed)
+
+	d, err = container.OpenDurableDictionary(dir, opts)
+	require.NoError(t, err)
+	assert.Equal(t, want, d.Map())
+	assert.NoError(t, d.Close())
+}
+
+// countRecords counts the records of a log from their length headers.
+func countRecords(log []byte) int {
+	n := 0
+	for len(log) >= 12 {
+		size := int(log[0]) | int(log[1])<<8 | int(log[2])<<16 | int(log[3])<<24
+		log = log[12+size:]
+		n++
+	}
+	return n
+}
+
+func TestDurableDictionaryRecovery(t *testing.T) {
+	dir := t.TempDir()
+	opts := container.DurableOptions[string, TestKey]{CompactEvery: -1}
+	d, err := container.OpenDurableDictionary(dir, opts)
+	require.NoError(t, err)
+	assert.NoError(t, d.Set("a", TestKey{"alpha", 1}))
+	assert.NoError(t, d.Set("b", TestKey{"bravo", 2}))
+	assert.NoError(t, d.Close())
+
+	// a crash in the middle of the last write leaves a torn record
+	path := filepath.Join(dir, "wal.log")
+	log, err := os.ReadFile(path)
+	require.NoError(t, err)
+	full := len(log)
+	require.NoError(t, os.WriteFile(path, log[:full-3], 0o644))
+
+	d, err = container.OpenDurableDictionary(dir, opts)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]TestKey{"a": {"alpha", 1}}, d.Map())
+	// the torn record is dropped and new writes follow the good ones
+	assert.NoError(t, d.Set("c", TestKey{"charlie", 3}))
+	assert.NoError(t, d.Close())
+
+	d, err = container.OpenDurableDictionary(dir, opts)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]TestKey{"a": {"alpha", 1}, "c": {"charlie", 3}}, d.Map())
+	assert.NoError(t, d.Close())
+
+	// so is a record with a bad checksum
+	log, err = os.ReadFile(path)
+	require.NoError(t, err)
+	log[len(log)-1] ^= 0xff
+	require.NoError(t, os.WriteFile(path, log, 0o644))
+	d, err = container.OpenDurableDictionary(dir, opts)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]TestKey{"a": {"alpha", 1}}, d.Map())
+
+	// a crash after replacing the snapshot but before emptying the log
+	// replays the log over the snapshot
+	assert.NoError(t, d.Set("d", TestKey{"delta", 4}))
+	_, err = d.Remove("a")
+	assert.NoError(t, err)
+	log, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NoError(t, d.Compact())
+	assert.NoError(t, d.Close())
+	require.NoError(t, os.WriteFile(path, log, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "snapshot.tmp"), []byte("partial"), 0o644))
+
+	d, err = container.OpenDurableDictionary(dir, opts)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]TestKey{"d": {"delta", 4}}, d.Map())
+	assert.NoFileExists(t, filepath.Join(dir, "snapshot.tmp"))
+	assert.NoError(t, d.Close())
+
+	// a damaged record followed by acknowledged ones is an error
+	d, err = container.OpenDurableDictionary(dir, opts)
+	require.NoError(t, err)
+	assert.NoError(t, d.Set("e", TestKey{"echo", 5}))
+	assert.NoError(t, d.Set("f", TestKey{"foxtrot", 6}))
+	assert.NoError(t, d.Close())
+	log, err = os.ReadFile(path)
+	require.NoError(t, err)
+	log[13] ^= 0xff
+	require.NoError(t, os.WriteFile(path, log, 0o644))
+	_, err = container.OpenDurableDictionary(dir, opts)
+	assert.ErrorIs(t, err, container.ErrCorruptDurableData)
+	log[13] ^= 0xff
+	require.NoError(t, os.WriteFile(path, log, 0o644))
+
+	// a damaged snapshot is an error
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "snapshot"), []byte{1, 2, 3}, 0o644))
+	_, err = container.OpenDurableDictionary(dir, opts)
+	assert.ErrorIs(t, err, container.ErrCorruptDurableData)
+	// and leaves the directory unlocked
+	_, err = container.OpenDurableDictionary(dir, opts)
+	assert.ErrorIs(t, err, container.ErrCorruptDurableData)
+}
+
+func TestDurableDictionaryDamagedLength(t *testing.T) {
+	dir := t.TempDir()
+	opts := container.DurableOptions[string, int]{CompactEvery: -1}
+	d, err := container.OpenDurableDictionary(dir, opts)
+	require.NoError(t, err)
+	for i, k := range []string{"a", "b", "c", "d"} {
+		assert.NoError(t, d.Set(k, i))
+	}
+	assert.NoError(t, d.Close())
+
+	// a length claiming more bytes than are left doesn't pass for a torn
+	// tail, the writes after it were acknowledged
+	path := filepath.Join(dir, "wal.log")
+	log, err := os.ReadFile(path)
+	require.NoError(t, err)
+	log[2] ^= 0x01
+	require.NoError(t, os.WriteFile(path, log, 0o644))
+	_, err = container.OpenDurableDictionary(dir, opts)
+	assert.ErrorIs(t, err, container.ErrCorruptDurableData)
+	after, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, log, after, "the log was truncated")
+
+	// restoring the length recovers every write
+	log[2] ^= 0x01
+	require.NoError(t, os.WriteFile(path, log, 0o644))
+	d, err = container.OpenDurableDictionary(dir, opts)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"a": 0, "b": 1, "c": 2, "d": 3}, d.Map())
+	assert.NoError(t, d.Close())
+}
+
+func TestDurableDictionaryZeroTail(t *testing.T) {
+	dir := t.TempDir()
+	opts := container.DurableOptions[string, int]{CompactEvery: -1}
+	d, err := container.OpenDurableDictionary(dir, opts)
+	require.NoError(t, err)
+	assert.NoError(t, d.Set("a", 1))
+	assert.NoError(t, d.Set("b", 2))
+	assert.NoError(t, d.Close())
+	path := filepath.Join(dir, "wal.log")
+	log, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	// a log extended with zeros by a crash is torn at the zeros
+	require.NoError(t, os.WriteFile(path, append(slices.Clone(log), make([]byte, 4096)...), 0o644))
+	d, err = container.OpenDurableDictionary(dir, opts)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"a": 1, "b": 2}, d.Map())
+	assert.NoError(t, d.Set("c", 3))
+	assert.NoError(t, d.Close())
+	d, err = container.OpenDurableDictionary(dir, opts)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"a": 1, "b": 2, "c": 3}, d.Map())
+	assert.NoError(t, d.Close())
+
+	// so is a record whose payload never made it
+	torn := slices.Clone(log)
+	clear(torn[len(torn)-3:])
+	require.NoError(t, os.WriteFile(path, append(torn, make([]byte, 100)...), 0o644))
+	d, err = container.OpenDurableDictionary(dir, opts)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"a": 1}, d.Map())
+	assert.NoError(t, d.Close())
+
+	// but zeros followed by more data are not a torn tail
+	zeros := append(slices.Clone(log), make([]byte, 100)...)
+	require.NoError(t, os.WriteFile(path, append(zeros, log...), 0o644))
+	_, err = container.OpenDurableDictionary(dir, opts)
+	assert.ErrorIs(t, err, container.ErrCorruptDurableData)
+}
+
+func TestDurableDictionaryGarbageHeader(t *testing.T) {
+	dir := t.TempDir()
+	opts := container.DurableOptions[string, int]{CompactEvery: -1}
+	d, err := container.OpenDurableDictionary(dir, opts)
+	require.NoError(t, err)
+	assert.NoError(t, d.Set("a", 1))
+	assert.NoError(t, d.Close())
+	path := filepath.Join(dir, "wal.log")
+	log, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	// a garbage header followed by more data is an error
+	garbage := []byte{0xde, 0xad, 0xbe, 0xef, 1, 2, 3, 4, 5, 6, 7, 8}
+	damaged := append(append(slices.Clone(log), garbage...), log...)
+	require.NoError(t, os.WriteFile(path, damaged, 0o644))
+	_, err = container.OpenDurableDictionary(dir, opts)
+	assert.ErrorIs(t, err, container.ErrCorruptDurableData)
+	after, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, damaged, after, "the log was truncated")
+
+	// even when only zeros follow it
+	damaged = append(append(slices.Clone(log), garbage...), make([]byte, 100)...)
+	require.NoError(t, os.WriteFile(path, damaged, 0o644))
+	_, err = container.OpenDurableDictionary(dir, opts)
+	assert.ErrorIs(t, err, container.ErrCorruptDurableData)
+}
+
+func TestDurableDictionaryCompactionFailure(t *testing.T) {
+	dir := t.TempDir()
+	var errs []error
+	opts := container.DurableOptions[int, int]{
+		CompactEvery:   2,
+		OnCompactError: func(err error) { errs = append(errs, err) },
+	}
+	d, err := container.OpenDurableDictionary(dir, opts)
+	require.NoError(t, err)
+	// the snapshot can't be written while a directory has its name
+	tmp := filepath.Join(dir, "snapshot.tmp")
+	require.NoError(t, os.MkdirAll(filepath.Join(tmp, "x"), 0o755))
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, d.Set(i, i))
+	}
+	// tried at 2 records, the next try waits for 4
+	assert.Len(t, errs, 1)
+	require.NoError(t, os.RemoveAll(tmp))
+	assert.NoError(t, d.Set(3, 3))
+	assert.Len(t, errs, 1)
+	log, err := os.ReadFile(filepath.Join(dir, "wal.log"))
+	require.NoError(t, err)
+	assert.Empty(t, log)
+	assert.NoError(t, d.Close())
+
+	d, err = container.OpenDurableDictionary(dir, opts)
+	require.NoError(t, err)
+	assert.Equal(t, map[int]int{0: 0, 1: 1, 2: 2, 3: 3}, d.Map())
+	assert.NoError(t, d.Close())
+}
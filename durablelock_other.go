@@ -0,0 +1,60 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd || windows)
+
+package container
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// lockDir creates the lock file of dir holding the PID of the process,
+// failing if it exists. A process that dies leaves the file behind; it is
+// taken over once its PID no longer runs. Two processes taking over the
+// same stale lock at once may both succeed.
+func lockDir(dir string) (*os.File, error) {
+	path := filepath.Join(dir, durableLockName)
+	for tries := 0; ; tries++ {
+		f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o644)
+		if err == nil {
+			if _, err := f.WriteString(strconv.Itoa(os.Getpid())); err != nil {
+				f.Close()
+				os.Remove(path)
+				return nil, err
+			}
+			return f, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		if tries > 0 || !staleLock(path) {
+			return nil, ErrDictionaryLocked
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+}
+
+// staleLock reports whether the lock file was left behind by a process
+// that no longer runs. When in doubt, the lock is not stale.
+func staleLock(path string) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 || pid == os.Getpid() {
+		return false
+	}
+	return !processRunning(pid)
+}
+
+func unlockDir(f *os.File) error {
+	err := f.Close()
+	if rerr := os.Remove(f.Name()); err == nil {
+		err = rerr
+	}
+	return err
+}
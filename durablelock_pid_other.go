@@ -0,0 +1,20 @@
+//go:build !unix && !windows
+
+package container
+
+import (
+	"os"
+	"runtime"
+	"strconv"
+)
+
+// processRunning reports whether a process with the PID runs. Plan 9 lists
+// its processes in /proc; elsewhere there is no telling, so it is assumed
+// to run.
+func processRunning(pid int) bool {
+	if runtime.GOOS != "plan9" {
+		return true
+	}
+	_, err := os.Stat("/proc/" + strconv.Itoa(pid))
+	return !os.IsNotExist(err)
+}
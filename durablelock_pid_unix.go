@@ -0,0 +1,20 @@
+//go:build unix && !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package container
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// processRunning reports whether a process with the PID runs, by sending
+// it the null signal.
+func processRunning(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return !errors.Is(err, os.ErrProcessDone)
+	}
+	defer p.Release()
+	return !errors.Is(p.Signal(syscall.Signal(0)), os.ErrProcessDone)
+}
@@ -0,0 +1,143 @@
+package container
+
+import (
+	"fmt"
+	"iter"
+)
+
+// Interval is a closed interval: it holds every point p with Lo <= p <= Hi.
+type Interval[T any] struct {
+	Lo, Hi T
+}
+
+// IntervalTreap is a persistent map from intervals to values that answers
+// overlap queries. It is an AugmentedTreap ordered by (Lo, Hi) where every
+// node tracks the largest Hi of its subtree, which lets queries skip the
+// subtrees that end before the queried range.
+//
+// All methods return new treaps; the receiver is never modified. The zero
+// value is not usable, use NewIntervalTreap instead.
+type IntervalTreap[T, V any] struct {
+	t       *AugmentedTreap[intervalEntry[T, V], intervalMax[T]]
+	compare CompareFn[T]
+}
+
+type intervalEntry[T, V any] struct {
+	interval Interval[T]
+	value    V
+}
+
+// intervalMax is the largest endpoint of a subtree, ok is false for empty
+// subtrees.
+type intervalMax[T any] struct {
+	hi T
+	ok bool
+}
+
+// NewIntervalTreap returns an empty treap with endpoints ordered by c.
+func NewIntervalTreap[T, V any](c CompareFn[T]) *IntervalTreap[T, V] {
+	order := func(left, right intervalEntry[T, V]) int {
+		if diff := c(left.interval.Lo, right.interval.Lo); diff != 0 {
+			return diff
+		}
+		return c(left.interval.Hi, right.interval.Hi)
+	}
+	m := Monoid[intervalEntry[T, V], intervalMax[T]]{
+		Measure: func(e intervalEntry[T, V]) intervalMax[T] {
+			return intervalMax[T]{e.interval.Hi, true}
+		},
+		Combine: func(left, right intervalMax[T]) intervalMax[T] {
+			if !left.ok || (right.ok && c(right.hi, left.hi) > 0) {
+				return right
+			}
+			return left
+		},
+	}
+	return &IntervalTreap[T, V]{
+		t:       NewAugmentedTreap(order, m, nil),
+		compare: c,
+	}
+}
+
+func (t *IntervalTreap[T, V]) with(at *AugmentedTreap[intervalEntry[T, V], intervalMax[T]]) *IntervalTreap[T, V] {
+	if at == t.t {
+		return t
+	}
+	return &IntervalTreap[T, V]{at, t.compare}
+}
+
+// Len returns the number of intervals in the treap.
+func (t *IntervalTreap[T, V]) Len() int {
+	return t.t.Len()
+}
+
+// Get returns the value of the interval and whether it was found.
+func (t *IntervalTreap[T, V]) Get(iv Interval[T]) (V, bool) {
+	e, ok := t.t.Get(intervalEntry[T, V]{interval: iv})
+	return e.value, ok
+}
+
+// Insert returns a treap with the interval set to v. It panics if iv.Lo is
+// greater than iv.Hi.
+func (t *IntervalTreap[T, V]) Insert(iv Interval[T], v V) *IntervalTreap[T, V] {
+	t.checkInterval(iv.Lo, iv.Hi)
+	return t.with(t.t.Insert(intervalEntry[T, V]{iv, v}))
+}
+
+// Delete returns a treap without the interval.
+func (t *IntervalTreap[T, V]) Delete(iv Interval[T]) *IntervalTreap[T, V] {
+	return t.with(t.t.Delete(intervalEntry[T, V]{interval: iv}))
+}
+
+// All returns an iterator over the intervals and their values, ordered by
+// (Lo, Hi).
+func (t *IntervalTreap[T, V]) All() iter.Seq2[Interval[T], V] {
+	return func(yield func(Interval[T], V) bool) {
+		for e := range t.t.All() {
+			if !yield(e.interval, e.value) {
+				return
+			}
+		}
+	}
+}
+
+// Overlapping returns an iterator over the intervals that share at least
+// one point with [lo, hi], ordered by (Lo, Hi). It panics if lo is greater
+// than hi.
+func (t *IntervalTreap[T, V]) Overlapping(lo, hi T) iter.Seq2[Interval[T], V] {
+	t.checkInterval(lo, hi)
+	return func(yield func(Interval[T], V) bool) {
+		t.overlapping(t.t.root, lo, hi, yield)
+	}
+}
+
+// Stabbing returns an iterator over the intervals that contain p, ordered
+// by (Lo, Hi).
+func (t *IntervalTreap[T, V]) Stabbing(p T) iter.Seq2[Interval[T], V] {
+	return t.Overlapping(p, p)
+}
+
+func (t *IntervalTreap[T, V]) checkInterval(lo, hi T) {
+	if t.compare(lo, hi) > 0 {
+		panic(fmt.Sprintf("container: invalid interval [%v, %v]", lo, hi))
+	}
+}
+
+func (t *IntervalTreap[T, V]) overlapping(n *Treap[augItem[intervalEntry[T, V], intervalMax[T]]], lo, hi T, yield func(Interval[T], V) bool) bool {
+	// nothing in this subtree reaches lo
+	if n == nil || t.compare(n.Value.summary.hi, lo) < 0 {
+		return true
+	}
+	if !t.overlapping(n.Left, lo, hi, yield) {
+		return false
+	}
+	// this interval and the ones to its right start after hi
+	e := n.Value.value
+	if t.compare(e.interval.Lo, hi) > 0 {
+		return true
+	}
+	if t.compare(e.interval.Hi, lo) >= 0 && !yield(e.interval, e.value) {
+		return false
+	}
+	return t.overlapping(n.Right, lo, hi, yield)
+}
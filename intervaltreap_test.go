@@ -0,0 +1,82 @@
+package container_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/gabstv/container"
+	"github.com/stretchr/testify/assert"
+)
+
+func collectIntervals[V any](seq func(func(container.Interval[int], V) bool)) []container.Interval[int] {
+	out := []container.Interval[int]{}
+	for iv := range seq {
+		out = append(out, iv)
+	}
+	return out
+}
+
+func TestIntervalTreap(t *testing.T) {
+	empty := container.NewIntervalTreap[int, string](intComparer[int])
+	x := empty.
+		Insert(container.Interval[int]{Lo: 10, Hi: 20}, "a").
+		Insert(container.Interval[int]{Lo: 15, Hi: 25}, "b").
+		Insert(container.Interval[int]{Lo: 30, Hi: 40}, "c").
+		Insert(container.Interval[int]{Lo: 5, Hi: 50}, "d").
+		Insert(container.Interval[int]{Lo: 10, Hi: 12}, "e")
+
+	assert.Equal(t, 5, x.Len())
+	assert.Equal(t, 0, empty.Len())
+
+	v, ok := x.Get(container.Interval[int]{Lo: 15, Hi: 25})
+	assert.True(t, ok)
+	assert.Equal(t, "b", v)
+	_, ok = x.Get(container.Interval[int]{Lo: 15, Hi: 26})
+	assert.False(t, ok)
+
+	assert.Equal(t, []container.Interval[int]{{5, 50}, {10, 20}, {15, 25}}, collectIntervals(x.Stabbing(18)))
+	assert.Equal(t, []container.Interval[int]{{5, 50}, {10, 12}, {10, 20}}, collectIntervals(x.Stabbing(10)))
+	assert.Equal(t, []container.Interval[int]{{5, 50}, {15, 25}, {30, 40}}, collectIntervals(x.Overlapping(25, 30)))
+	assert.Empty(t, collectIntervals(x.Overlapping(51, 60)))
+	assert.Empty(t, collectIntervals(x.Overlapping(0, 4)))
+
+	y := x.Delete(container.Interval[int]{Lo: 5, Hi: 50})
+	assert.Equal(t, []container.Interval[int]{{15, 25}}, collectIntervals(y.Overlapping(21, 29)))
+	assert.Equal(t, 5, x.Len(), "the original treap was modified")
+	assert.Equal(t, 4, y.Len())
+
+	values := []string{}
+	for _, v := range y.All() {
+		values = append(values, v)
+	}
+	assert.Equal(t, []string{"e", "a", "b", "c"}, values)
+
+	assert.Panics(t, func() {
+		x.Insert(container.Interval[int]{Lo: 2, Hi: 1}, "bad")
+	})
+	assert.Panics(t, func() {
+		x.Overlapping(5, 4)
+	})
+}
+
+func TestIntervalTreapAgainstBruteForce(t *testing.T) {
+	x := container.NewIntervalTreap[int, int](intComparer[int])
+	intervals := map[container.Interval[int]]bool{}
+	for kk := 0; kk < 2000; kk++ {
+		lo := rand.Intn(10000)
+		iv := container.Interval[int]{Lo: lo, Hi: lo + rand.Intn(300)}
+		intervals[iv] = true
+		x = x.Insert(iv, kk)
+	}
+	for kk := 0; kk < 200; kk++ {
+		lo := rand.Intn(10000)
+		hi := lo + rand.Intn(100)
+		expected := 0
+		for iv := range intervals {
+			if iv.Lo <= hi && iv.Hi >= lo {
+				expected++
+			}
+		}
+		assert.Len(t, collectIntervals(x.Overlapping(lo, hi)), expected)
+	}
+}
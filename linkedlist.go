@@ -1,5 +1,7 @@
 package container
 
+import "iter"
+
 type node[T comparable] struct {
 	data   T
 	prev   *node[T]
@@ -104,6 +106,65 @@ func (ll *LinkedList[T]) Remove(d T) bool {
 	return false
 }
 
+// All returns an iterator over the indexes and data of the items, from
+// first to last. It iterates a copy of the list taken when the iteration
+// starts, so the loop may modify the list: removed items are still
+// visited, and added ones are not.
+func (ll *LinkedList[T]) All() iter.Seq2[int, T] {
+	return func(yield func(int, T) bool) {
+		for i, d := range ll.snapshot() {
+			if !yield(i, d) {
+				return
+			}
+		}
+	}
+}
+
+// Keys returns an iterator over the indexes of the items, from first to
+// last. Like All, it iterates a copy of the list.
+func (ll *LinkedList[T]) Keys() iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for i := range ll.length {
+			if !yield(i) {
+				return
+			}
+		}
+	}
+}
+
+// Values returns an iterator over the data of the items, from first to
+// last. Like All, it iterates a copy of the list.
+func (ll *LinkedList[T]) Values() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for _, d := range ll.snapshot() {
+			if !yield(d) {
+				return
+			}
+		}
+	}
+}
+
+// Backward returns an iterator over the indexes and data of the items,
+// from last to first. Like All, it iterates a copy of the list.
+func (ll *LinkedList[T]) Backward() iter.Seq2[int, T] {
+	return func(yield func(int, T) bool) {
+		items := ll.snapshot()
+		for i := len(items) - 1; i >= 0; i-- {
+			if !yield(i, items[i]) {
+				return
+			}
+		}
+	}
+}
+
+func (ll *LinkedList[T]) snapshot() []T {
+	items := make([]T, 0, ll.length)
+	for n := ll.head; n != nil; n = n.next {
+		items = append(items, n.data)
+	}
+	return items
+}
+
 // Pop removes the last item of the list and returns its data.
 func (ll *LinkedList[T]) Pop() T {
 	var d T
@@ -191,3 +252,49 @@ func (ll *LinkedList[T]) Clear() {
 	ll.head = nil
 	ll.tail = nil
 }
+
+// MoveToFront moves the node to the beginning of the list. It returns false
+// if the node doesn't belong to the list.
+func (ll *LinkedList[T]) MoveToFront(item Node[T]) bool {
+	n, ok := item.(*node[T])
+	if !ok || n == nil || n.parent != ll {
+		return false
+	}
+	if ll.head == n {
+		return true
+	}
+	n.Remove()
+	n.parent = ll
+	n.next = ll.head
+	if ll.head != nil {
+		ll.head.prev = n
+	} else {
+		ll.tail = n
+	}
+	ll.head = n
+	ll.length++
+	return true
+}
+
+// MoveToBack moves the node to the end of the list. It returns false if the
+// node doesn't belong to the list.
+func (ll *LinkedList[T]) MoveToBack(item Node[T]) bool {
+	n, ok := item.(*node[T])
+	if !ok || n == nil || n.parent != ll {
+		return false
+	}
+	if ll.tail == n {
+		return true
+	}
+	n.Remove()
+	n.parent = ll
+	n.prev = ll.tail
+	if ll.tail != nil {
+		ll.tail.next = n
+	} else {
+		ll.head = n
+	}
+	ll.tail = n
+	ll.length++
+	return true
+}
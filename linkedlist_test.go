@@ -1,6 +1,7 @@
 package container_test
 
 import (
+	"slices"
 	"testing"
 
 	"github.com/gabstv/container"
@@ -56,3 +57,100 @@ func TestDoubleLinkedList(t *testing.T) {
 	assert.Equal(t, 1234, ll.Unshift(1234).Data())
 	assert.Nil(t, ll.First().Prev().Prev())
 }
+
+func linkedListValues(ll *container.LinkedList[int]) []int {
+	values := make([]int, ll.Len())
+	backward := make([]int, ll.Len())
+	first, last := ll.First(), ll.Last()
+	for i := range values {
+		values[i] = first.Data()
+		backward[len(backward)-1-i] = last.Data()
+		first, last = first.Next(), last.Prev()
+	}
+	if !assert.ObjectsAreEqual(values, backward) {
+		panic("inconsistent links")
+	}
+	return values
+}
+
+func TestLinkedListMove(t *testing.T) {
+	var ll container.LinkedList[int]
+	n1 := ll.Push(1)
+	n2 := ll.Push(2)
+	n3 := ll.Push(3)
+
+	assert.True(t, ll.MoveToFront(n3))
+	assert.Equal(t, []int{3, 1, 2}, linkedListValues(&ll))
+	assert.True(t, ll.MoveToFront(n3))
+	assert.Equal(t, []int{3, 1, 2}, linkedListValues(&ll))
+	assert.True(t, ll.MoveToFront(n1))
+	assert.Equal(t, []int{1, 3, 2}, linkedListValues(&ll))
+	assert.True(t, ll.MoveToBack(n1))
+	assert.Equal(t, []int{3, 2, 1}, linkedListValues(&ll))
+	assert.True(t, ll.MoveToBack(n1))
+	assert.Equal(t, []int{3, 2, 1}, linkedListValues(&ll))
+	assert.True(t, ll.MoveToBack(n3))
+	assert.Equal(t, []int{2, 1, 3}, linkedListValues(&ll))
+
+	var other container.LinkedList[int]
+	assert.False(t, other.MoveToFront(n2))
+	assert.False(t, other.MoveToBack(n2))
+	assert.True(t, n2.Remove())
+	assert.False(t, ll.MoveToFront(n2))
+	assert.Equal(t, []int{1, 3}, linkedListValues(&ll))
+
+	single := other.Push(7)
+	assert.True(t, other.MoveToFront(single))
+	assert.True(t, other.MoveToBack(single))
+	assert.Equal(t, []int{7}, linkedListValues(&other))
+}
+
+func TestLinkedListIterators(t *testing.T) {
+	var ll container.LinkedList[int]
+	assert.Empty(t, slices.Collect(ll.Values()))
+	for i := 1; i <= 5; i++ {
+		ll.Push(i * 10)
+	}
+	indexes, values := []int{}, []int{}
+	for i, v := range ll.All() {
+		indexes = append(indexes, i)
+		values = append(values, v)
+	}
+	assert.Equal(t, []int{0, 1, 2, 3, 4}, indexes)
+	assert.Equal(t, []int{10, 20, 30, 40, 50}, values)
+
+	indexes, values = indexes[:0], values[:0]
+	for i, v := range ll.Backward() {
+		indexes = append(indexes, i)
+		values = append(values, v)
+		if v == 30 {
+			break
+		}
+	}
+	assert.Equal(t, []int{4, 3, 2}, indexes)
+	assert.Equal(t, []int{50, 40, 30}, values)
+
+	assert.Equal(t, []int{0, 1, 2, 3, 4}, slices.Collect(ll.Keys()))
+
+	// the loop iterates a copy, so it can remove items, even the next ones,
+	// and the items it pushes are not visited
+	values = values[:0]
+	for v := range ll.Values() {
+		values = append(values, v)
+		if v == 10 || v == 20 {
+			ll.Remove(v)
+			ll.Remove(v + 10)
+			ll.Push(v + 1)
+		}
+	}
+	assert.Equal(t, []int{10, 20, 30, 40, 50}, values)
+	assert.Equal(t, []int{40, 50, 11, 21}, linkedListValues(&ll))
+
+	values = values[:0]
+	for _, v := range ll.Backward() {
+		values = append(values, v)
+		ll.Remove(v)
+	}
+	assert.Equal(t, []int{21, 11, 50, 40}, values)
+	assert.Zero(t, ll.Len())
+}
@@ -2,6 +2,7 @@ package container
 
 import (
 	"encoding/json"
+	"iter"
 
 	"golang.org/x/exp/constraints"
 )
@@ -115,6 +116,62 @@ func (l *List2D[T]) Height() int {
 	return l.height
 }
 
+// Point is a position in a List2D.
+type Point struct {
+	X, Y int
+}
+
+// All returns an iterator over the positions and values of the list, row
+// by row. It reads the live list: values set during the iteration are seen
+// if they weren't visited yet, but resizing it doesn't affect the
+// iteration, which goes on over the old contents.
+func (l *List2D[T]) All() iter.Seq2[Point, T] {
+	return func(yield func(Point, T) bool) {
+		data, width := l.data, l.width
+		for i := range data {
+			if !yield(Point{i % width, i / width}, data[i]) {
+				return
+			}
+		}
+	}
+}
+
+// Backward returns an iterator over the positions and values of the list
+// in the reverse order of All. See All for how it deals with changes.
+func (l *List2D[T]) Backward() iter.Seq2[Point, T] {
+	return func(yield func(Point, T) bool) {
+		data, width := l.data, l.width
+		for i := len(data) - 1; i >= 0; i-- {
+			if !yield(Point{i % width, i / width}, data[i]) {
+				return
+			}
+		}
+	}
+}
+
+// Keys returns an iterator over the positions of the list, row by row.
+func (l *List2D[T]) Keys() iter.Seq[Point] {
+	return func(yield func(Point) bool) {
+		for p := range l.All() {
+			if !yield(p) {
+				return
+			}
+		}
+	}
+}
+
+// Values returns an iterator over the values of the list, row by row. See
+// All for how it deals with changes.
+func (l *List2D[T]) Values() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for _, v := range l.All() {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
 func NewList2D[T any](width, height int) *List2D[T] {
 	l := new(List2D[T])
 	l.width = width
@@ -1,6 +1,7 @@
 package container_test
 
 import (
+	"slices"
 	"testing"
 
 	"github.com/gabstv/container"
@@ -96,3 +97,41 @@ func TestNewList2DFrom2DSlice(t *testing.T) {
 	assert.Equal(t, 3, l.Get(1, 1))
 	assert.Equal(t, 9, l.Get(3, 2))
 }
+
+func TestList2DIterators(t *testing.T) {
+	l := container.NewList2DFrom2DSlice([][]int{
+		{1, 2, 3},
+		{4, 5, 6},
+	})
+	points, values := []container.Point{}, []int{}
+	for p, v := range l.All() {
+		points = append(points, p)
+		values = append(values, v)
+	}
+	assert.Equal(t, []container.Point{{0, 0}, {1, 0}, {2, 0}, {0, 1}, {1, 1}, {2, 1}}, points)
+	assert.Equal(t, []int{1, 2, 3, 4, 5, 6}, values)
+	assert.Equal(t, points, slices.Collect(l.Keys()))
+	assert.Equal(t, values, slices.Collect(l.Values()))
+
+	points = points[:0]
+	for p, v := range l.Backward() {
+		points = append(points, p)
+		if v == 5 {
+			break
+		}
+	}
+	assert.Equal(t, []container.Point{{2, 1}, {1, 1}}, points)
+
+	// values set ahead of the iteration are seen, resizing is not
+	values = values[:0]
+	for p, v := range l.All() {
+		values = append(values, v)
+		if p == (container.Point{0, 0}) {
+			l.Set(1, 0, 20)
+			l.Resize(1, 1)
+		}
+	}
+	assert.Equal(t, []int{1, 20, 3, 4, 5, 6}, values)
+	assert.Equal(t, []int{1}, slices.Collect(l.Values()))
+	assert.Empty(t, slices.Collect(container.NewList2D[int](0, 3).Values()))
+}
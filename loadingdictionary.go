@@ -0,0 +1,229 @@
+package container
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// LoadingConfig configures a LoadingDictionary.
+type LoadingConfig struct {
+	// TTL is how long loaded values are kept. Zero means they never
+	// expire.
+	TTL time.Duration
+	// ErrorTTL is how long load errors are kept, so that a failing key
+	// isn't loaded again on every Get. Zero means errors aren't kept.
+	ErrorTTL time.Duration
+	// RefreshAhead, if set, makes Get reload a value in the background
+	// when it is accessed less than RefreshAhead before it expires. Get
+	// keeps returning the current value until the new one is loaded. If
+	// the refresh fails, the current value is kept and the next refresh
+	// waits for ErrorTTL.
+	RefreshAhead time.Duration
+	// JanitorInterval is how often expired entries are deleted, see
+	// TTLConfig.
+	JanitorInterval time.Duration
+	// Clock tells the current time, time.Now if nil.
+	Clock func() time.Time
+}
+
+// LoadingDictionary is a thread-safe cache that loads missing values with
+// a loader function. Concurrent Gets of the same missing key share a single
+// call to the loader.
+type LoadingDictionary[KT comparable, VT any] struct {
+	load         func(ctx context.Context, k KT) (VT, error)
+	cache        *TTLDictionary[KT, loadResult[VT]]
+	ttl          time.Duration
+	errorTTL     time.Duration
+	refreshAhead time.Duration
+
+	lock  sync.Mutex
+	calls map[KT]*loadCall[VT]
+}
+
+type loadResult[VT any] struct {
+	value VT
+	err   error
+	// retry is when the value may be refreshed again after a failed
+	// refresh, zero if none failed
+	retry time.Time
+}
+
+// loadCall is a load in flight.
+type loadCall[VT any] struct {
+	done   chan struct{}
+	result loadResult[VT]
+	// waiters is the number of Gets waiting for the load, it is cancelled
+	// when all of them gave up. Background refreshes are never cancelled.
+	waiters    int
+	background bool
+	cancel     context.CancelFunc
+}
+
+// NewLoadingDictionary returns an empty dictionary loading missing values
+// with load. If cfg has a janitor interval, Close must be called to stop
+// the janitor.
+func NewLoadingDictionary[KT comparable, VT any](load func(ctx context.Context, k KT) (VT, error), cfg LoadingConfig) *LoadingDictionary[KT, VT] {
+	return &LoadingDictionary[KT, VT]{
+		load: load,
+		cache: NewTTLDictionary(TTLConfig[KT, loadResult[VT]]{
+			JanitorInterval: cfg.JanitorInterval,
+			Clock:           cfg.Clock,
+		}),
+		ttl:          cfg.TTL,
+		errorTTL:     cfg.ErrorTTL,
+		refreshAhead: cfg.RefreshAhead,
+		calls:        make(map[KT]*loadCall[VT]),
+	}
+}
+
+// Close stops the janitor.
+func (l *LoadingDictionary[KT, VT]) Close() error {
+	return l.cache.Close()
+}
+
+// Get returns the value of the key, loading it if it isn't cached. If the
+// load fails, Get returns its error, which is cached for the error TTL.
+//
+// If ctx is done before the value is loaded, Get returns the error of the
+// context. The load itself is only cancelled once every Get waiting for it
+// gave up, and a later Get starts a new one; its context keeps the values
+// of the first ctx.
+func (l *LoadingDictionary[KT, VT]) Get(ctx context.Context, k KT) (VT, error) {
+	if e, ok := l.cache.lookup(k); ok {
+		if l.refreshAhead > 0 && !e.expires.IsZero() {
+			now := l.cache.now()
+			if !now.Before(e.expires.Add(-l.refreshAhead)) && !now.Before(e.value.retry) {
+				l.refresh(ctx, k)
+			}
+		}
+		return e.value.value, e.value.err
+	}
+
+	l.lock.Lock()
+	// it may have been loaded since
+	if e, ok := l.cache.lookup(k); ok {
+		l.lock.Unlock()
+		return e.value.value, e.value.err
+	}
+	call, ok := l.calls[k]
+	if !ok {
+		call = l.start(ctx, k, false)
+	}
+	call.waiters++
+	l.lock.Unlock()
+
+	select {
+	case <-call.done:
+		return call.result.value, call.result.err
+	case <-ctx.Done():
+		l.lock.Lock()
+		call.waiters--
+		if call.waiters == 0 && !call.background {
+			call.cancel()
+			// the next Get starts a new load instead of joining this one
+			if l.calls[k] == call {
+				delete(l.calls, k)
+			}
+		}
+		l.lock.Unlock()
+		var zv VT
+		return zv, ctx.Err()
+	}
+}
+
+// refresh starts a background load of the key, unless one is in flight.
+func (l *LoadingDictionary[KT, VT]) refresh(ctx context.Context, k KT) {
+	l.lock.Lock()
+	if _, ok := l.calls[k]; !ok {
+		l.start(ctx, k, true)
+	}
+	l.lock.Unlock()
+}
+
+// start loads the key in a new goroutine. The lock must be held.
+func (l *LoadingDictionary[KT, VT]) start(ctx context.Context, k KT, background bool) *loadCall[VT] {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	call := &loadCall[VT]{
+		done:       make(chan struct{}),
+		background: background,
+		cancel:     cancel,
+	}
+	l.calls[k] = call
+	go func() {
+		defer cancel()
+		v, err := l.load(ctx, k)
+		call.result = loadResult[VT]{value: v, err: err}
+
+		l.lock.Lock()
+		// Set and Remove drop the calls in flight, their results are stale
+		if l.calls[k] == call {
+			delete(l.calls, k)
+			switch {
+			case err == nil:
+				l.cache.SetWithTTL(k, call.result, l.ttl)
+			case background:
+				// keep the current value after a failed refresh, and
+				// hold back the next one for the error TTL
+				if e, ok := l.cache.lookup(k); ok && l.errorTTL > 0 {
+					e.value.retry = l.cache.now().Add(l.errorTTL)
+					l.cache.store(k, e)
+				}
+			case ctx.Err() == nil && l.errorTTL > 0:
+				l.cache.SetWithTTL(k, call.result, l.errorTTL)
+			}
+		}
+		l.lock.Unlock()
+		close(call.done)
+	}()
+	return call
+}
+
+// Loading reports whether the key is being loaded, and how many Gets wait
+// for the load. Background refreshes have no waiters of their own.
+func (l *LoadingDictionary[KT, VT]) Loading(k KT) (waiters int, ok bool) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	call, ok := l.calls[k]
+	if !ok {
+		return 0, false
+	}
+	return call.waiters, true
+}
+
+// GetIfPresent returns the cached value of the key without loading it. It
+// returns false if the key isn't cached or its load failed.
+func (l *LoadingDictionary[KT, VT]) GetIfPresent(k KT) (VT, bool) {
+	e, ok := l.cache.lookup(k)
+	if !ok || e.value.err != nil {
+		var zv VT
+		return zv, false
+	}
+	return e.value.value, true
+}
+
+// Set caches a value for the key, replacing the result of any load in
+// flight.
+func (l *LoadingDictionary[KT, VT]) Set(k KT, v VT) {
+	l.lock.Lock()
+	delete(l.calls, k)
+	l.cache.SetWithTTL(k, loadResult[VT]{value: v}, l.ttl)
+	l.lock.Unlock()
+}
+
+// Remove drops the cached value or error of the key, so that the next Get
+// loads it again. The result of any load in flight is dropped as well.
+func (l *LoadingDictionary[KT, VT]) Remove(k KT) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	delete(l.calls, k)
+	return l.cache.Remove(k)
+}
+
+// Clear drops all the cached values and errors.
+func (l *LoadingDictionary[KT, VT]) Clear() {
+	l.lock.Lock()
+	clear(l.calls)
+	l.cache.Clear()
+	l.lock.Unlock()
+}
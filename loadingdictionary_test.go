@@ -0,0 +1,264 @@
+package container_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gabstv/container"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadingDictionary(t *testing.T) {
+	clock := newFakeClock()
+	var calls atomic.Int32
+	l := container.NewLoadingDictionary(func(ctx context.Context, k int) (string, error) {
+		n := calls.Add(1)
+		return strconv.Itoa(k) + "#" + strconv.Itoa(int(n)), nil
+	}, container.LoadingConfig{TTL: time.Minute, Clock: clock.Now})
+	defer l.Close()
+
+	ctx := context.Background()
+	_, ok := l.GetIfPresent(1)
+	assert.False(t, ok)
+	v, err := l.Get(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "1#1", v)
+	v, _ = l.Get(ctx, 1)
+	assert.Equal(t, "1#1", v)
+	v, ok = l.GetIfPresent(1)
+	assert.True(t, ok)
+	assert.Equal(t, "1#1", v)
+
+	clock.Advance(time.Minute)
+	v, _ = l.Get(ctx, 1)
+	assert.Equal(t, "1#2", v)
+
+	l.Set(2, "two")
+	v, _ = l.Get(ctx, 2)
+	assert.Equal(t, "two", v)
+	assert.True(t, l.Remove(2))
+	v, _ = l.Get(ctx, 2)
+	assert.Equal(t, "2#3", v)
+
+	l.Clear()
+	_, ok = l.GetIfPresent(1)
+	assert.False(t, ok)
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestLoadingDictionarySingleFlight(t *testing.T) {
+	release := make(chan struct{})
+	var calls atomic.Int32
+	l := container.NewLoadingDictionary(func(ctx context.Context, k string) (int, error) {
+		calls.Add(1)
+		<-release
+		return len(k), nil
+	}, container.LoadingConfig{})
+
+	var wg sync.WaitGroup
+	results := make([]int, 10)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			v, err := l.Get(context.Background(), "abc")
+			assert.NoError(t, err)
+			results[i] = v
+		}(i)
+	}
+	waitFor(t, func() bool {
+		waiters, _ := l.Loading("abc")
+		return waiters == len(results)
+	})
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), calls.Load())
+	for _, v := range results {
+		assert.Equal(t, 3, v)
+	}
+}
+
+func TestLoadingDictionaryErrors(t *testing.T) {
+	clock := newFakeClock()
+	errBackend := errors.New("backend down")
+	var calls atomic.Int32
+	l := container.NewLoadingDictionary(func(ctx context.Context, k string) (int, error) {
+		calls.Add(1)
+		return 0, errBackend
+	}, container.LoadingConfig{ErrorTTL: time.Second, Clock: clock.Now})
+
+	_, err := l.Get(context.Background(), "a")
+	assert.ErrorIs(t, err, errBackend)
+	_, err = l.Get(context.Background(), "a")
+	assert.ErrorIs(t, err, errBackend)
+	assert.Equal(t, int32(1), calls.Load())
+	_, ok := l.GetIfPresent("a")
+	assert.False(t, ok)
+
+	clock.Advance(time.Second)
+	_, err = l.Get(context.Background(), "a")
+	assert.ErrorIs(t, err, errBackend)
+	assert.Equal(t, int32(2), calls.Load())
+
+	// without an error TTL, errors are not cached
+	l = container.NewLoadingDictionary(func(ctx context.Context, k string) (int, error) {
+		calls.Add(1)
+		return 0, errBackend
+	}, container.LoadingConfig{})
+	l.Get(context.Background(), "a")
+	l.Get(context.Background(), "a")
+	assert.Equal(t, int32(4), calls.Load())
+}
+
+func TestLoadingDictionaryCancel(t *testing.T) {
+	started := make(chan struct{}, 2)
+	release := make(chan int)
+	cancelled := make(chan error, 1)
+	// cancelled loads only return once finish is closed
+	finish := make(chan struct{})
+	defer close(finish)
+	l := container.NewLoadingDictionary(func(ctx context.Context, k string) (int, error) {
+		started <- struct{}{}
+		select {
+		case v := <-release:
+			return v, nil
+		case <-ctx.Done():
+			cancelled <- ctx.Err()
+			<-finish
+			return 0, ctx.Err()
+		}
+	}, container.LoadingConfig{ErrorTTL: time.Hour})
+
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	errs := make(chan error, 2)
+	go func() {
+		_, err := l.Get(ctx1, "a")
+		errs <- err
+	}()
+	go func() {
+		_, err := l.Get(ctx2, "a")
+		errs <- err
+	}()
+	<-started
+	waitFor(t, func() bool {
+		waiters, _ := l.Loading("a")
+		return waiters == 2
+	})
+
+	// the load keeps going while someone waits for it
+	cancel1()
+	assert.ErrorIs(t, <-errs, context.Canceled)
+	select {
+	case <-cancelled:
+		t.Fatal("load cancelled with a waiter left")
+	case <-time.After(10 * time.Millisecond):
+	}
+	cancel2()
+	assert.ErrorIs(t, <-errs, context.Canceled)
+	assert.ErrorIs(t, <-cancelled, context.Canceled)
+
+	// a Get while the cancelled load is still returning starts a new load
+	// instead of joining it
+	type result struct {
+		v   int
+		err error
+	}
+	results := make(chan result, 1)
+	go func() {
+		v, err := l.Get(context.Background(), "a")
+		results <- result{v, err}
+	}()
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no new load started")
+	}
+	release <- 7
+	assert.Equal(t, result{7, nil}, <-results)
+}
+
+func TestLoadingDictionaryRefreshAhead(t *testing.T) {
+	clock := newFakeClock()
+	var calls atomic.Int32
+	release := make(chan struct{})
+	l := container.NewLoadingDictionary(func(ctx context.Context, k string) (int, error) {
+		switch n := calls.Add(1); n {
+		case 3:
+			return 0, errors.New("refresh failed")
+		case 4:
+			<-release
+			return int(n), nil
+		default:
+			return int(n), nil
+		}
+	}, container.LoadingConfig{TTL: time.Minute, ErrorTTL: 2 * time.Second, RefreshAhead: 10 * time.Second, Clock: clock.Now})
+
+	ctx := context.Background()
+	v, _ := l.Get(ctx, "a")
+	assert.Equal(t, 1, v)
+	clock.Advance(45 * time.Second)
+	v, _ = l.Get(ctx, "a")
+	assert.Equal(t, 1, v)
+	assert.Equal(t, int32(1), calls.Load())
+
+	// within the refresh window, the current value is returned while the
+	// new one loads
+	clock.Advance(10 * time.Second)
+	v, _ = l.Get(ctx, "a")
+	assert.Equal(t, 1, v)
+	waitFor(t, func() bool {
+		v, _ := l.GetIfPresent("a")
+		return v == 2
+	})
+
+	// a failed refresh keeps the current value, and the next refresh
+	// waits for the error TTL
+	clock.Advance(55 * time.Second)
+	v, err := l.Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, v)
+	waitFor(t, func() bool {
+		_, loading := l.Loading("a")
+		return !loading
+	})
+	assert.Equal(t, int32(3), calls.Load())
+	for i := 0; i < 10; i++ {
+		if i == 5 {
+			clock.Advance(time.Second)
+		}
+		v, err = l.Get(ctx, "a")
+		assert.NoError(t, err)
+		assert.Equal(t, 2, v)
+		_, loading := l.Loading("a")
+		assert.False(t, loading)
+	}
+	assert.Equal(t, int32(3), calls.Load())
+
+	clock.Advance(time.Second)
+	v, _ = l.Get(ctx, "a")
+	assert.Equal(t, 2, v)
+	_, loading := l.Loading("a")
+	assert.True(t, loading)
+	close(release)
+	waitFor(t, func() bool {
+		v, _ := l.GetIfPresent("a")
+		return v == 4
+	})
+	assert.Equal(t, int32(4), calls.Load())
+}
+
+// waitFor polls cond until it is true or a few seconds passed.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); !cond(); time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met")
+		}
+	}
+}
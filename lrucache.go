@@ -0,0 +1,195 @@
+package container
+
+import (
+	"fmt"
+	"sync"
+)
+
+// LRUCache is a thread-safe cache holding up to a fixed number of entries.
+// When it is full, setting a new key evicts the least recently used entry.
+type LRUCache[K comparable, V any] struct {
+	lock     sync.Mutex
+	capacity int
+	// order holds the keys from the most to the least recently used
+	order   LinkedList[K]
+	items   map[K]*lruEntry[K, V]
+	onEvict func(k K, v V)
+	stats   LRUStats
+}
+
+type lruEntry[K comparable, V any] struct {
+	value V
+	node  Node[K]
+}
+
+// LRUStats counts the lookups of an LRUCache.
+type LRUStats struct {
+	Hits, Misses, Evictions uint64
+}
+
+// NewLRUCache returns an empty cache holding up to capacity entries. If
+// onEvict isn't nil, it is called with every entry evicted to make room;
+// it isn't called for entries deleted by Remove or Clear. It runs without
+// holding the lock, so it may use the cache. NewLRUCache panics if capacity
+// is less than one.
+func NewLRUCache[K comparable, V any](capacity int, onEvict func(k K, v V)) *LRUCache[K, V] {
+	checkCapacity(capacity)
+	return &LRUCache[K, V]{
+		capacity: capacity,
+		items:    make(map[K]*lruEntry[K, V]),
+		onEvict:  onEvict,
+	}
+}
+
+func checkCapacity(capacity int) {
+	if capacity < 1 {
+		panic(fmt.Sprintf("container: invalid cache capacity %d", capacity))
+	}
+}
+
+// Get returns the value of the key and marks it as the most recently used.
+func (c *LRUCache[K, V]) Get(k K) (V, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	e, ok := c.items[k]
+	if !ok {
+		c.stats.Misses++
+		var zv V
+		return zv, false
+	}
+	c.stats.Hits++
+	c.order.MoveToFront(e.node)
+	return e.value, true
+}
+
+// Peek returns the value of the key without marking it as used or counting
+// the lookup in the stats.
+func (c *LRUCache[K, V]) Peek(k K) (V, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	e, ok := c.items[k]
+	if !ok {
+		var zv V
+		return zv, false
+	}
+	return e.value, true
+}
+
+// Contains returns true if the cache holds the key, without marking it as
+// used.
+func (c *LRUCache[K, V]) Contains(k K) bool {
+	_, ok := c.Peek(k)
+	return ok
+}
+
+// Set sets a key=value pair and marks it as the most recently used. It
+// returns true if an entry was evicted to make room.
+func (c *LRUCache[K, V]) Set(k K, v V) bool {
+	c.lock.Lock()
+	if e, ok := c.items[k]; ok {
+		e.value = v
+		c.order.MoveToFront(e.node)
+		c.lock.Unlock()
+		return false
+	}
+	c.items[k] = &lruEntry[K, V]{value: v, node: c.order.Unshift(k)}
+	evicted := c.evict()
+	c.lock.Unlock()
+	c.notify(evicted)
+	return len(evicted) > 0
+}
+
+// Remove deletes the key from the cache.
+func (c *LRUCache[K, V]) Remove(k K) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	e, ok := c.items[k]
+	if !ok {
+		return false
+	}
+	e.node.Remove()
+	delete(c.items, k)
+	return true
+}
+
+// Clear removes all entries. The stats are kept.
+func (c *LRUCache[K, V]) Clear() {
+	c.lock.Lock()
+	c.order.Clear()
+	c.items = make(map[K]*lruEntry[K, V])
+	c.lock.Unlock()
+}
+
+// Len returns the number of entries in the cache.
+func (c *LRUCache[K, V]) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return len(c.items)
+}
+
+// Cap returns the capacity of the cache.
+func (c *LRUCache[K, V]) Cap() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.capacity
+}
+
+// Resize changes the capacity of the cache, evicting the least recently
+// used entries that don't fit anymore. It returns how many were evicted and
+// panics if capacity is less than one.
+func (c *LRUCache[K, V]) Resize(capacity int) int {
+	checkCapacity(capacity)
+	c.lock.Lock()
+	c.capacity = capacity
+	evicted := c.evict()
+	c.lock.Unlock()
+	c.notify(evicted)
+	return len(evicted)
+}
+
+// Keys returns the keys from the most to the least recently used.
+func (c *LRUCache[K, V]) Keys() []K {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	keys := make([]K, 0, len(c.items))
+	for n := c.order.First(); len(keys) < len(c.items); n = n.Next() {
+		keys = append(keys, n.Data())
+	}
+	return keys
+}
+
+// Stats returns the counts of the lookups done by Get and of evictions.
+func (c *LRUCache[K, V]) Stats() LRUStats {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.stats
+}
+
+// ResetStats sets the counts returned by Stats back to zero.
+func (c *LRUCache[K, V]) ResetStats() {
+	c.lock.Lock()
+	c.stats = LRUStats{}
+	c.lock.Unlock()
+}
+
+// evict drops the least recently used entries over capacity and returns
+// them. The lock must be held.
+func (c *LRUCache[K, V]) evict() []MI[K, V] {
+	var evicted []MI[K, V]
+	for len(c.items) > c.capacity {
+		k := c.order.Pop()
+		evicted = append(evicted, MI[K, V]{Key: k, Value: c.items[k].value})
+		delete(c.items, k)
+		c.stats.Evictions++
+	}
+	return evicted
+}
+
+func (c *LRUCache[K, V]) notify(evicted []MI[K, V]) {
+	if c.onEvict == nil {
+		return
+	}
+	for _, e := range evicted {
+		c.onEvict(e.Key, e.Value)
+	}
+}
@@ -0,0 +1,109 @@
+package container_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/gabstv/container"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRUCache(t *testing.T) {
+	var evicted []string
+	c := container.NewLRUCache(3, func(k string, v int) {
+		evicted = append(evicted, k)
+	})
+	assert.False(t, c.Set("a", 1))
+	assert.False(t, c.Set("b", 2))
+	assert.False(t, c.Set("c", 3))
+	assert.Equal(t, []string{"c", "b", "a"}, c.Keys())
+
+	v, ok := c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, v)
+	assert.Equal(t, []string{"a", "c", "b"}, c.Keys())
+	v, ok = c.Peek("b")
+	assert.True(t, ok)
+	assert.Equal(t, 2, v)
+	assert.Equal(t, []string{"a", "c", "b"}, c.Keys())
+
+	assert.True(t, c.Set("d", 4))
+	assert.Equal(t, []string{"b"}, evicted)
+	assert.False(t, c.Contains("b"))
+	_, ok = c.Get("b")
+	assert.False(t, ok)
+
+	// updating a key bumps it without evicting
+	assert.False(t, c.Set("c", 30))
+	assert.Equal(t, []string{"c", "d", "a"}, c.Keys())
+	v, _ = c.Peek("c")
+	assert.Equal(t, 30, v)
+
+	assert.Equal(t, container.LRUStats{Hits: 1, Misses: 1, Evictions: 1}, c.Stats())
+	c.ResetStats()
+	assert.Equal(t, container.LRUStats{}, c.Stats())
+
+	assert.True(t, c.Remove("d"))
+	assert.False(t, c.Remove("d"))
+	assert.Equal(t, []string{"c", "a"}, c.Keys())
+	assert.Equal(t, 2, c.Len())
+	c.Clear()
+	assert.Equal(t, 0, c.Len())
+	assert.Empty(t, c.Keys())
+	assert.Equal(t, []string{"b"}, evicted)
+}
+
+func TestLRUCacheResize(t *testing.T) {
+	var evicted []int
+	c := container.NewLRUCache(5, func(k, v int) {
+		evicted = append(evicted, k)
+	})
+	for i := 0; i < 5; i++ {
+		c.Set(i, i)
+	}
+	c.Get(0)
+	assert.Equal(t, 3, c.Resize(2))
+	assert.Equal(t, []int{1, 2, 3}, evicted)
+	assert.Equal(t, []int{0, 4}, c.Keys())
+	assert.Equal(t, 2, c.Cap())
+
+	assert.Equal(t, 0, c.Resize(4))
+	c.Set(5, 5)
+	c.Set(6, 6)
+	assert.Equal(t, []int{6, 5, 0, 4}, c.Keys())
+	assert.Equal(t, []int{1, 2, 3}, evicted)
+
+	assert.Panics(t, func() { c.Resize(0) })
+	assert.Panics(t, func() { container.NewLRUCache[int, int](-1, nil) })
+}
+
+func TestLRUCacheEvictCallbackReentrant(t *testing.T) {
+	var c *container.LRUCache[int, int]
+	c = container.NewLRUCache(1, func(k, v int) {
+		// the lock isn't held during the callback
+		assert.False(t, c.Contains(k))
+	})
+	c.Set(1, 1)
+	c.Set(2, 2)
+	assert.Equal(t, uint64(1), c.Stats().Evictions)
+}
+
+func TestLRUCacheConcurrent(t *testing.T) {
+	c := container.NewLRUCache[int, int](64, nil)
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				c.Set((g*i)%100, i)
+				c.Get(i % 100)
+			}
+		}(g)
+	}
+	wg.Wait()
+	assert.Equal(t, 64, c.Len())
+	assert.Len(t, c.Keys(), 64)
+	s := c.Stats()
+	assert.Equal(t, uint64(8000), s.Hits+s.Misses)
+}
@@ -0,0 +1,118 @@
+package container
+
+// PersistentMap is an immutable ordered map backed by a Treap. Every update
+// returns a new map; older versions stay valid and share most of their nodes
+// with the newer ones, so taking a snapshot is free.
+//
+// The zero value is not usable, use NewPersistentMap instead.
+type PersistentMap[K, V any] struct {
+	root     *Treap[mapEntry[K, V]]
+	compare  CompareFn[mapEntry[K, V]]
+	priority PriorityFn[K]
+}
+
+type mapEntry[K, V any] struct {
+	key   K
+	value V
+}
+
+// NewPersistentMap returns an empty map ordered by the given key comparer.
+// p picks the priority of inserted keys, it is random if nil. With a
+// deterministic PriorityFn two maps holding the same keys share the same
+// shape, no matter the order they were set in.
+func NewPersistentMap[K, V any](c CompareFn[K], p PriorityFn[K]) *PersistentMap[K, V] {
+	if p == nil {
+		p = RandomPriority[K]()
+	}
+	return &PersistentMap[K, V]{
+		compare: func(left, right mapEntry[K, V]) int {
+			return c(left.key, right.key)
+		},
+		priority: p,
+	}
+}
+
+func (m *PersistentMap[K, V]) with(root *Treap[mapEntry[K, V]]) *PersistentMap[K, V] {
+	return &PersistentMap[K, V]{
+		root:     root,
+		compare:  m.compare,
+		priority: m.priority,
+	}
+}
+
+// Len returns the number of keys in the map.
+func (m *PersistentMap[K, V]) Len() int {
+	return m.root.Len()
+}
+
+// Get returns the value of the key and whether it was found.
+func (m *PersistentMap[K, V]) Get(k K) (V, bool) {
+	n := m.root.Find(mapEntry[K, V]{key: k}, m.compare)
+	if n == nil {
+		var zv V
+		return zv, false
+	}
+	return n.Value.value, true
+}
+
+// Contains returns true if the map contains the key.
+func (m *PersistentMap[K, V]) Contains(k K) bool {
+	return m.root.Find(mapEntry[K, V]{key: k}, m.compare) != nil
+}
+
+// Set returns a new map with the key set to the value. The receiver is not
+// modified.
+func (m *PersistentMap[K, V]) Set(k K, v V) *PersistentMap[K, V] {
+	leaf := newTreap(mapEntry[K, V]{key: k, value: v}, m.priority(k), nil, nil, nil)
+	return m.with(m.root.Union(leaf, m.compare, true))
+}
+
+// Delete returns a new map without the key. If the key is not present the
+// receiver itself is returned.
+func (m *PersistentMap[K, V]) Delete(k K) *PersistentMap[K, V] {
+	entry := mapEntry[K, V]{key: k}
+	if m.root.Find(entry, m.compare) == nil {
+		return m
+	}
+	return m.with(m.root.Delete(entry, m.compare))
+}
+
+// ForEach calls fn for every key=value pair in ascending key order.
+func (m *PersistentMap[K, V]) ForEach(fn func(k K, v V)) {
+	m.root.ForEach(func(e mapEntry[K, V]) {
+		fn(e.key, e.value)
+	})
+}
+
+// Keys returns the keys in ascending order.
+func (m *PersistentMap[K, V]) Keys() []K {
+	keys := make([]K, 0, m.root.Len())
+	m.root.ForEach(func(e mapEntry[K, V]) {
+		keys = append(keys, e.key)
+	})
+	return keys
+}
+
+// Union returns a map with the keys of both maps. In case of duplicate keys,
+// the overwrite field controls whether the value of the receiver is kept or
+// replaced by the value in other.
+func (m *PersistentMap[K, V]) Union(other *PersistentMap[K, V], overwrite bool) *PersistentMap[K, V] {
+	root := m.root.Union(other.root, m.compare, overwrite)
+	return m.with(root)
+}
+
+// Intersection returns a map with the keys present in both maps. The values
+// are taken from the receiver.
+func (m *PersistentMap[K, V]) Intersection(other *PersistentMap[K, V]) *PersistentMap[K, V] {
+	// Treap.Intersection keeps the value of whichever node has the higher
+	// priority, so go through Diff which always keeps the receiver's values.
+	root := m.root.Diff(m.root.Diff(other.root, m.compare), m.compare)
+	return m.with(root)
+}
+
+// Diff returns a map with the keys of the receiver that are not present in
+// other.
+func (m *PersistentMap[K, V]) Diff(other *PersistentMap[K, V]) *PersistentMap[K, V] {
+	root := m.root.Diff(other.root, m.compare)
+	return m.with(root)
+}
@@ -0,0 +1,81 @@
+package container_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gabstv/container"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPersistentMap(t *testing.T) {
+	m0 := container.NewPersistentMap[string, int](strings.Compare, nil)
+	m1 := m0.Set("b", 2).Set("a", 1).Set("c", 3)
+	m2 := m1.Set("b", 20).Delete("a")
+
+	assert.Equal(t, 0, m0.Len())
+	assert.Equal(t, 3, m1.Len())
+	assert.Equal(t, 2, m2.Len())
+
+	v, ok := m1.Get("b")
+	assert.True(t, ok)
+	assert.Equal(t, 2, v)
+	v, ok = m2.Get("b")
+	assert.True(t, ok)
+	assert.Equal(t, 20, v)
+	assert.True(t, m1.Contains("a"))
+	assert.False(t, m2.Contains("a"))
+	_, ok = m2.Get("z")
+	assert.False(t, ok)
+
+	assert.Equal(t, []string{"a", "b", "c"}, m1.Keys())
+	assert.Equal(t, []string{"b", "c"}, m2.Keys())
+	assert.Equal(t, m2, m2.Delete("z"))
+
+	values := []int{}
+	m1.ForEach(func(k string, v int) {
+		values = append(values, v)
+	})
+	assert.Equal(t, []int{1, 2, 3}, values)
+}
+
+func TestPersistentMapSetOperations(t *testing.T) {
+	empty := container.NewPersistentMap[int, string](intComparer[int], nil)
+	left := empty.Set(1, "l1").Set(2, "l2").Set(3, "l3")
+	right := empty.Set(2, "r2").Set(3, "r3").Set(4, "r4")
+
+	union := left.Union(right, false)
+	assert.Equal(t, 4, union.Len())
+	assert.Equal(t, []int{1, 2, 3, 4}, union.Keys())
+	v, _ := union.Get(2)
+	assert.Equal(t, "l2", v)
+
+	union = left.Union(right, true)
+	v, _ = union.Get(2)
+	assert.Equal(t, "r2", v)
+
+	inter := left.Intersection(right)
+	assert.Equal(t, 2, inter.Len())
+	assert.Equal(t, []int{2, 3}, inter.Keys())
+	v, _ = inter.Get(3)
+	assert.Equal(t, "l3", v)
+
+	diff := left.Diff(right)
+	assert.Equal(t, 1, diff.Len())
+	assert.Equal(t, []int{1}, diff.Keys())
+}
+
+func TestPersistentMapPriority(t *testing.T) {
+	seen := []int{}
+	p := func(k int) int {
+		seen = append(seen, k)
+		return container.IntegerPriority[int]()(k)
+	}
+	empty := container.NewPersistentMap[int, string](intComparer[int], p)
+	m := empty.Set(3, "c").Set(1, "a").Set(2, "b").Set(1, "A")
+	assert.Equal(t, []int{3, 1, 2, 1}, seen)
+	assert.Equal(t, []int{1, 2, 3}, m.Keys())
+	v, _ := m.Get(1)
+	assert.Equal(t, "A", v)
+	assert.Equal(t, 3, m.Delete(2).Set(2, "b").Len())
+}
@@ -0,0 +1,214 @@
+package container
+
+import (
+	"fmt"
+	"iter"
+	"math/rand"
+)
+
+// Rope is a persistent sequence built on an implicit-key treap: nodes are
+// ordered by their position instead of by comparing values, and positions
+// are derived from subtree sizes. Insert, Delete, Concat, Slice and Reverse
+// all run in O(log n) and return new ropes that share most of their nodes
+// with the receiver, which is never modified.
+//
+// The zero value is an empty rope ready to use. Methods taking positions
+// panic if they are out of range, like slice indexing does.
+type Rope[T any] struct {
+	root *Treap[ropeItem[T]]
+}
+
+// ropeItem is the value of a rope node. Its priority is the one of the
+// treap node and its position is implied by the subtree sizes.
+type ropeItem[T any] struct {
+	value T
+	// reversed marks the whole subtree as reversed. It is applied lazily,
+	// as nodes are copied by split and join.
+	reversed bool
+}
+
+// NewRope returns a rope holding the items, built in linear time.
+func NewRope[T any](items ...T) *Rope[T] {
+	var b treapBuilder[ropeItem[T]]
+	for _, v := range items {
+		b.push(ropeItem[T]{value: v}, rand.Int())
+	}
+	return &Rope[T]{b.finish()}
+}
+
+// Len returns the number of items in the rope.
+func (r *Rope[T]) Len() int {
+	return r.root.Len()
+}
+
+// At returns the item at position i.
+func (r *Rope[T]) At(i int) T {
+	r.checkIndex(i, r.Len()-1)
+	n, reversed := r.root, false
+	for {
+		reversed = reversed != n.Value.reversed
+		left, right := n.Left, n.Right
+		if reversed {
+			left, right = right, left
+		}
+		size := left.Len()
+		switch {
+		case i < size:
+			n = left
+		case i == size:
+			return n.Value.value
+		default:
+			i -= size + 1
+			n = right
+		}
+	}
+}
+
+// Set returns a rope with the item at position i replaced by v.
+func (r *Rope[T]) Set(i int, v T) *Rope[T] {
+	r.checkIndex(i, r.Len()-1)
+	left, right := ropeSplit(r.root, i)
+	_, right = ropeSplit(right, 1)
+	return &Rope[T]{ropeJoin(ropeJoin(left, newRopeLeaf(v)), right)}
+}
+
+// Insert returns a rope with v inserted at position i, shifting the items
+// from i onwards. i may be equal to Len to append.
+func (r *Rope[T]) Insert(i int, v T) *Rope[T] {
+	r.checkIndex(i, r.Len())
+	left, right := ropeSplit(r.root, i)
+	return &Rope[T]{ropeJoin(ropeJoin(left, newRopeLeaf(v)), right)}
+}
+
+// Append returns a rope with the items added at the end.
+func (r *Rope[T]) Append(items ...T) *Rope[T] {
+	return r.Concat(NewRope(items...))
+}
+
+// Delete returns a rope without the item at position i.
+func (r *Rope[T]) Delete(i int) *Rope[T] {
+	r.checkIndex(i, r.Len()-1)
+	left, right := ropeSplit(r.root, i)
+	_, right = ropeSplit(right, 1)
+	return &Rope[T]{ropeJoin(left, right)}
+}
+
+// Concat returns a rope with the items of r followed by the items of other.
+func (r *Rope[T]) Concat(other *Rope[T]) *Rope[T] {
+	return &Rope[T]{ropeJoin(r.root, other.root)}
+}
+
+// Slice returns a rope with the items from position i up to, but not
+// including, j.
+func (r *Rope[T]) Slice(i, j int) *Rope[T] {
+	if i < 0 || j < i || j > r.Len() {
+		panic(fmt.Sprintf("container: rope slice [%d:%d] out of range with length %d", i, j, r.Len()))
+	}
+	_, right := ropeSplit(r.root, i)
+	mid, _ := ropeSplit(right, j-i)
+	return &Rope[T]{mid}
+}
+
+// Reverse returns a rope with the items in reverse order. It is O(1), the
+// work is done lazily by later operations.
+func (r *Rope[T]) Reverse() *Rope[T] {
+	return &Rope[T]{ropeFlip(r.root)}
+}
+
+// All returns an iterator over the positions and items of the rope.
+func (r *Rope[T]) All() iter.Seq2[int, T] {
+	return func(yield func(int, T) bool) {
+		i := 0
+		ropeEach(r.root, false, func(v T) bool {
+			if !yield(i, v) {
+				return false
+			}
+			i++
+			return true
+		})
+	}
+}
+
+// ToSlice returns the items of the rope as a slice.
+func (r *Rope[T]) ToSlice() []T {
+	items := make([]T, 0, r.Len())
+	ropeEach(r.root, false, func(v T) bool {
+		items = append(items, v)
+		return true
+	})
+	return items
+}
+
+func (r *Rope[T]) checkIndex(i, last int) {
+	if i < 0 || i > last {
+		panic(fmt.Sprintf("container: rope index %d out of range with length %d", i, r.Len()))
+	}
+}
+
+func newRopeLeaf[T any](v T) *Treap[ropeItem[T]] {
+	return newTreap(ropeItem[T]{value: v}, rand.Int(), nil, nil, nil)
+}
+
+// ropeFlip returns a copy of n with the reversed mark toggled.
+func ropeFlip[T any](n *Treap[ropeItem[T]]) *Treap[ropeItem[T]] {
+	if n == nil {
+		return nil
+	}
+	cp := *n
+	cp.Value.reversed = !cp.Value.reversed
+	return &cp
+}
+
+// ropeChildren returns the children of n in sequence order, pushing the
+// reversed mark of n down to them.
+func ropeChildren[T any](n *Treap[ropeItem[T]]) (left, right *Treap[ropeItem[T]]) {
+	if !n.Value.reversed {
+		return n.Left, n.Right
+	}
+	return ropeFlip(n.Right), ropeFlip(n.Left)
+}
+
+// ropeSplit splits the rope into its first k items and the remaining ones.
+func ropeSplit[T any](n *Treap[ropeItem[T]], k int) (left, right *Treap[ropeItem[T]]) {
+	if n == nil {
+		return nil, nil
+	}
+	l, r := ropeChildren(n)
+	item := ropeItem[T]{value: n.Value.value}
+	if k <= l.Len() {
+		left, right = ropeSplit(l, k)
+		return left, newTreap(item, n.Priority, right, r, nil)
+	}
+	left, right = ropeSplit(r, k-l.Len()-1)
+	return newTreap(item, n.Priority, l, left, nil), right
+}
+
+// ropeJoin concatenates two ropes.
+func ropeJoin[T any](n, other *Treap[ropeItem[T]]) *Treap[ropeItem[T]] {
+	if n == nil {
+		return other
+	}
+	if other == nil {
+		return n
+	}
+	if n.Priority >= other.Priority {
+		l, r := ropeChildren(n)
+		return newTreap(ropeItem[T]{value: n.Value.value}, n.Priority, l, ropeJoin(r, other), nil)
+	}
+	l, r := ropeChildren(other)
+	return newTreap(ropeItem[T]{value: other.Value.value}, other.Priority, ropeJoin(n, l), r, nil)
+}
+
+// ropeEach calls yield for every item in sequence order. reversed is the
+// reversed state inherited from the ancestors of n.
+func ropeEach[T any](n *Treap[ropeItem[T]], reversed bool, yield func(T) bool) bool {
+	if n == nil {
+		return true
+	}
+	reversed = reversed != n.Value.reversed
+	left, right := n.Left, n.Right
+	if reversed {
+		left, right = right, left
+	}
+	return ropeEach(left, reversed, yield) && yield(n.Value.value) && ropeEach(right, reversed, yield)
+}
@@ -0,0 +1,93 @@
+package container_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/gabstv/container"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRope(t *testing.T) {
+	var empty container.Rope[rune]
+	assert.Equal(t, 0, empty.Len())
+	assert.Equal(t, []rune{}, empty.ToSlice())
+
+	r := container.NewRope([]rune("hello world")...)
+	assert.Equal(t, 11, r.Len())
+	assert.Equal(t, 'w', r.At(6))
+
+	r2 := r.Insert(5, ',').Insert(12, '!')
+	assert.Equal(t, "hello, world!", string(r2.ToSlice()))
+	assert.Equal(t, "hello world", string(r.ToSlice()))
+
+	assert.Equal(t, "hello world!", string(r2.Delete(5).ToSlice()))
+	assert.Equal(t, "world", string(r.Slice(6, 11).ToSlice()))
+	assert.Equal(t, "", string(r.Slice(3, 3).ToSlice()))
+	assert.Equal(t, "dlrow olleh", string(r.Reverse().ToSlice()))
+	assert.Equal(t, "hello world", string(r.Reverse().Reverse().ToSlice()))
+	assert.Equal(t, "Hello world", string(r.Set(0, 'H').ToSlice()))
+	assert.Equal(t, "hello world, hello world", string(r.Append(',', ' ').Concat(r).ToSlice()))
+
+	// edits on a reversed rope push the reversal down lazily
+	rev := r.Reverse()
+	assert.Equal(t, 'd', rev.At(0))
+	assert.Equal(t, "dlrow", string(rev.Slice(0, 5).ToSlice()))
+	assert.Equal(t, "world", string(rev.Slice(0, 5).Reverse().ToSlice()))
+	assert.Equal(t, "dlrow_olleh", string(rev.Set(5, '_').ToSlice()))
+
+	positions := []int{}
+	for i, v := range r.All() {
+		assert.Equal(t, r.At(i), v)
+		positions = append(positions, i)
+		if i == 2 {
+			break
+		}
+	}
+	assert.Equal(t, []int{0, 1, 2}, positions)
+
+	assert.Panics(t, func() { r.At(11) })
+	assert.Panics(t, func() { r.Insert(12, 'x') })
+	assert.Panics(t, func() { r.Delete(-1) })
+	assert.Panics(t, func() { r.Slice(5, 4) })
+}
+
+func TestRopeAgainstSlice(t *testing.T) {
+	rand.Seed(42)
+
+	r := container.NewRope[int]()
+	model := []int{}
+	for kk := 0; kk < 3000; kk++ {
+		switch op := rand.Intn(6); {
+		case op < 3 || len(model) == 0:
+			i := rand.Intn(len(model) + 1)
+			r = r.Insert(i, kk)
+			model = append(model[:i], append([]int{kk}, model[i:]...)...)
+		case op == 3:
+			i := rand.Intn(len(model))
+			r = r.Delete(i)
+			model = append(model[:i], model[i+1:]...)
+		case op == 4:
+			i := rand.Intn(len(model) + 1)
+			j := i + rand.Intn(len(model)-i+1)
+			reversed := r.Slice(i, j).Reverse()
+			r = r.Slice(0, i).Concat(reversed).Concat(r.Slice(j, r.Len()))
+			for a, b := i, j-1; a < b; a, b = a+1, b-1 {
+				model[a], model[b] = model[b], model[a]
+			}
+		default:
+			i := rand.Intn(len(model))
+			r = r.Set(i, -kk)
+			model[i] = -kk
+		}
+		if r.Len() != len(model) {
+			t.Fatal("Len diverged")
+		}
+	}
+	assert.Equal(t, model, r.ToSlice())
+	for i := range model {
+		if r.At(i) != model[i] {
+			t.Fatal("At diverged", i)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package container
 
 import (
 	"errors"
+	"iter"
 	"sync"
 )
 
@@ -52,6 +53,40 @@ func (s *Set[T]) Each(fn func(item T)) {
 	}
 }
 
+// All returns an iterator over the items, in no particular order; a set
+// has no order, so it has no Backward either. Unlike Each, which holds the
+// lock while fn runs, it iterates a copy of the set taken when the
+// iteration starts, so the loop may modify the set.
+func (s *Set[T]) All() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for _, item := range s.snapshot() {
+			if !yield(item) {
+				return
+			}
+		}
+	}
+}
+
+// Keys is the same as All; a set's items are its keys.
+func (s *Set[T]) Keys() iter.Seq[T] {
+	return s.All()
+}
+
+// Values is the same as All; a set's items are its values.
+func (s *Set[T]) Values() iter.Seq[T] {
+	return s.All()
+}
+
+func (s *Set[T]) snapshot() []T {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	items := make([]T, 0, len(s.m))
+	for item := range s.m {
+		items = append(items, item)
+	}
+	return items
+}
+
 func (s *Set[T]) Contains(item T) bool {
 	s.lock.RLock()
 	defer s.lock.RUnlock()
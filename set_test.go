@@ -0,0 +1,50 @@
+package container_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/gabstv/container"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetAll(t *testing.T) {
+	var s container.Set[int]
+	assert.Empty(t, slices.Collect(s.All()))
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, s.Add(i))
+	}
+	assert.ErrorIs(t, s.Add(3), container.ErrItemExists)
+	assert.Equal(t, []int{0, 1, 2, 3, 4}, slices.Sorted(s.All()))
+
+	// the loop iterates a snapshot, so it can modify the set
+	for item := range s.All() {
+		s.Remove(item)
+		s.Add(item + 10)
+	}
+	assert.Equal(t, []int{10, 11, 12, 13, 14}, slices.Sorted(s.All()))
+
+	count := 0
+	for range s.All() {
+		count++
+		break
+	}
+	assert.Equal(t, 1, count)
+}
+
+func TestSetIterators(t *testing.T) {
+	var s container.Set[string]
+	assert.Empty(t, slices.Collect(s.Keys()))
+	for _, item := range []string{"a", "b", "c", "d"} {
+		assert.NoError(t, s.Add(item))
+	}
+	want := []string{"a", "b", "c", "d"}
+	assert.Equal(t, want, slices.Sorted(s.Keys()))
+	assert.Equal(t, want, slices.Sorted(s.Values()))
+
+	// the loop iterates a snapshot, so it can modify the set
+	for item := range s.Keys() {
+		s.Remove(item)
+	}
+	assert.Empty(t, slices.Collect(s.Values()))
+}
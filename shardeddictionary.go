@@ -0,0 +1,274 @@
+package container
+
+import (
+	"encoding/binary"
+	"hash/maphash"
+	"iter"
+	"math"
+	"reflect"
+	"runtime"
+	"unsafe"
+)
+
+// ShardedDictionary is a thread-safe dictionary that spreads its keys over
+// several Dictionary shards, each with its own lock, so that goroutines
+// working on different keys rarely wait for each other. It has the same
+// methods as Dictionary.
+//
+// The zero value is not usable, use NewShardedDictionary instead.
+type ShardedDictionary[KT comparable, VT any] struct {
+	shards []dictShard[KT, VT]
+	mask   uint64
+	hash   func(k KT) uint64
+}
+
+// cacheLine is the cache line size assumed when padding shards.
+const cacheLine = 64
+
+// dictShard pads a Dictionary to a multiple of the cache line size, so that
+// the locks of neighbouring shards don't share a cache line. The size of a
+// Dictionary doesn't depend on its type parameters.
+type dictShard[KT comparable, VT any] struct {
+	Dictionary[KT, VT]
+	_ [cacheLine - unsafe.Sizeof(Dictionary[int, int]{})%cacheLine]byte
+}
+
+// NewShardedDictionary returns an empty dictionary with the given number of
+// shards, rounded up to a power of two. If shards is zero or less, it picks
+// four shards per CPU. hash maps keys to shards; if nil, keys are hashed
+// with hash/maphash using a random seed, walking composite keys with
+// reflect. Passing a hash avoids that cost for such keys.
+func NewShardedDictionary[KT comparable, VT any](shards int, hash func(k KT) uint64) *ShardedDictionary[KT, VT] {
+	if shards <= 0 {
+		shards = 4 * runtime.GOMAXPROCS(0)
+	}
+	n := 1
+	for n < shards {
+		n <<= 1
+	}
+	if hash == nil {
+		seed := maphash.MakeSeed()
+		hash = func(k KT) uint64 {
+			return hashComparable(seed, k)
+		}
+	}
+	return &ShardedDictionary[KT, VT]{
+		shards: make([]dictShard[KT, VT], n),
+		mask:   uint64(n - 1),
+		hash:   hash,
+	}
+}
+
+// hashComparable hashes k so that equal keys get equal hashes. Strings and
+// integers are hashed directly, other keys are walked with reflect.
+func hashComparable[KT comparable](seed maphash.Seed, k KT) uint64 {
+	var h maphash.Hash
+	h.SetSeed(seed)
+	switch k := any(k).(type) {
+	case string:
+		h.WriteString(k)
+	case int:
+		writeUint64(&h, uint64(k))
+	case int64:
+		writeUint64(&h, uint64(k))
+	case uint64:
+		writeUint64(&h, k)
+	default:
+		hashValue(&h, reflect.ValueOf(k))
+	}
+	return h.Sum64()
+}
+
+func hashValue(h *maphash.Hash, v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Invalid:
+		h.WriteByte(0)
+	case reflect.String:
+		h.WriteString(v.String())
+	case reflect.Bool:
+		if v.Bool() {
+			h.WriteByte(1)
+		} else {
+			h.WriteByte(0)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		writeUint64(h, uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		writeUint64(h, v.Uint())
+	case reflect.Float32, reflect.Float64:
+		writeFloat(h, v.Float())
+	case reflect.Complex64, reflect.Complex128:
+		writeFloat(h, real(v.Complex()))
+		writeFloat(h, imag(v.Complex()))
+	case reflect.Pointer, reflect.Chan, reflect.UnsafePointer:
+		writeUint64(h, uint64(v.Pointer()))
+	case reflect.Interface:
+		hashValue(h, v.Elem())
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			hashValue(h, v.Index(i))
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			// == ignores blank fields, so must the hash
+			if v.Type().Field(i).Name == "_" {
+				continue
+			}
+			hashValue(h, v.Field(i))
+		}
+	default:
+		panic("container: unhashable key type " + v.Type().String())
+	}
+}
+
+func writeUint64(h *maphash.Hash, x uint64) {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], x)
+	h.Write(buf[:])
+}
+
+// writeFloat hashes -0 like 0, as they compare equal.
+func writeFloat(h *maphash.Hash, f float64) {
+	if f == 0 {
+		f = 0
+	}
+	writeUint64(h, math.Float64bits(f))
+}
+
+func (m *ShardedDictionary[KT, VT]) shard(k KT) *Dictionary[KT, VT] {
+	return &m.shards[m.hash(k)&m.mask].Dictionary
+}
+
+// Set sets a key=value pair in the map.
+func (m *ShardedDictionary[KT, VT]) Set(k KT, v VT) {
+	m.shard(k).Set(k, v)
+}
+
+// Get returns the value of the key, or the zero value if it isn't set.
+func (m *ShardedDictionary[KT, VT]) Get(k KT) VT {
+	return m.shard(k).Get(k)
+}
+
+// Contains returns true if the dictionary contains the key.
+func (m *ShardedDictionary[KT, VT]) Contains(k KT) bool {
+	return m.shard(k).Contains(k)
+}
+
+// Remove deletes the key from the dictionary.
+func (m *ShardedDictionary[KT, VT]) Remove(k KT) bool {
+	return m.shard(k).Remove(k)
+}
+
+// Pop removes and returns the value of the key.
+func (m *ShardedDictionary[KT, VT]) Pop(k KT) (VT, bool) {
+	return m.shard(k).Pop(k)
+}
+
+// Clear removes all keys. The shards are cleared one after the other, so
+// keys set concurrently may survive it.
+func (m *ShardedDictionary[KT, VT]) Clear() {
+	for i := range m.shards {
+		m.shards[i].Clear()
+	}
+}
+
+// Each calls the given function for each key=value pair in the map.
+// It copies and iterates one shard at a time, so setting a key inside
+// the loop will not affect the shards already copied, but the pairs
+// don't come from a single point in time.
+func (m *ShardedDictionary[KT, VT]) Each(fn func(KT, VT) bool) {
+	more := true
+	for i := 0; i < len(m.shards) && more; i++ {
+		m.shards[i].Each(func(k KT, v VT) bool {
+			more = fn(k, v)
+			return more
+		})
+	}
+}
+
+// All returns an iterator over the key=value pairs of the map, in no
+// particular order. Like Each, it iterates a copy of one shard at a time.
+func (m *ShardedDictionary[KT, VT]) All() iter.Seq2[KT, VT] {
+	return func(yield func(KT, VT) bool) {
+		m.Each(yield)
+	}
+}
+
+// Keys returns an iterator over the keys of the map, in no particular
+// order. Like All, it iterates a copy of one shard at a time.
+func (m *ShardedDictionary[KT, VT]) Keys() iter.Seq[KT] {
+	return func(yield func(KT) bool) {
+		m.Each(func(k KT, _ VT) bool {
+			return yield(k)
+		})
+	}
+}
+
+// Values returns an iterator over the values of the map, in no particular
+// order. Like All, it iterates a copy of one shard at a time.
+func (m *ShardedDictionary[KT, VT]) Values() iter.Seq[VT] {
+	return func(yield func(VT) bool) {
+		m.Each(func(_ KT, v VT) bool {
+			return yield(v)
+		})
+	}
+}
+
+// Map returns a map copy of the dictionary. Like Each, it copies one shard
+// at a time. As with Dictionary.Map, it is nil if nothing was ever set.
+func (m *ShardedDictionary[KT, VT]) Map() map[KT]VT {
+	var m2 map[KT]VT
+	for i := range m.shards {
+		s := &m.shards[i]
+		s.lock.RLock()
+		if s.m != nil && m2 == nil {
+			m2 = make(map[KT]VT)
+		}
+		for k, v := range s.m {
+			m2[k] = v
+		}
+		s.lock.RUnlock()
+	}
+	return m2
+}
+
+// GetOrSet returns the value of the key if it is set. Otherwise it sets the
+// key to v and returns v. loaded is true if the value was already set.
+func (m *ShardedDictionary[KT, VT]) GetOrSet(k KT, v VT) (actual VT, loaded bool) {
+	return m.shard(k).GetOrSet(k, v)
+}
+
+// SetIfAbsent sets the key to v if it isn't set, and returns true if it
+// did.
+func (m *ShardedDictionary[KT, VT]) SetIfAbsent(k KT, v VT) bool {
+	return m.shard(k).SetIfAbsent(k, v)
+}
+
+// CompareAndSwap sets the key to new if its value is equal to old, and
+// returns true if it did. It panics if the values are not of a comparable
+// type.
+func (m *ShardedDictionary[KT, VT]) CompareAndSwap(k KT, old, new VT) bool {
+	return m.shard(k).CompareAndSwap(k, old, new)
+}
+
+// CompareAndDelete deletes the key if its value is equal to old, and
+// returns true if it did. It panics if the values are not of a comparable
+// type.
+func (m *ShardedDictionary[KT, VT]) CompareAndDelete(k KT, old VT) bool {
+	return m.shard(k).CompareAndDelete(k, old)
+}
+
+// Update replaces the value of the key with the result of fn, if the key
+// is set. It returns true if the key was set. fn runs under the lock of
+// the key's shard, so it must not call other methods of the dictionary.
+func (m *ShardedDictionary[KT, VT]) Update(k KT, fn func(v VT) VT) bool {
+	return m.shard(k).Update(k, fn)
+}
+
+// Compute calls fn with the value of the key and whether it is set. If fn
+// returns keep, the key is set to the returned value, otherwise the key is
+// deleted. Compute returns what fn returned. fn runs under the lock of the
+// key's shard, so it must not call other methods of the dictionary.
+func (m *ShardedDictionary[KT, VT]) Compute(k KT, fn func(old VT, exists bool) (new VT, keep bool)) (VT, bool) {
+	return m.shard(k).Compute(k, fn)
+}
@@ -0,0 +1,225 @@
+package container_test
+
+import (
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"unsafe"
+
+	"github.com/gabstv/container"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShardedDictionary(t *testing.T) {
+	d := container.NewShardedDictionary[TestKey, int](0, nil)
+	d.Set(TestKey{Name: "alpha", Score: 100}, 1)
+	d.Set(TestKey{Name: "bravo", Score: 300}, 2)
+	d.Set(TestKey{Name: "charlie", Score: 700}, 8)
+	assert.Equal(t, 1, d.Get(TestKey{Name: "alpha", Score: 100}))
+	assert.Equal(t, 2, d.Get(TestKey{Name: "bravo", Score: 300}))
+	assert.Equal(t, 0, d.Get(TestKey{Name: "delta", Score: 300}))
+	assert.True(t, d.Contains(TestKey{Name: "charlie", Score: 700}))
+	assert.False(t, d.Contains(TestKey{Name: "charlie", Score: 100}))
+
+	assert.True(t, d.Remove(TestKey{Name: "charlie", Score: 700}))
+	assert.False(t, d.Remove(TestKey{Name: "charlie", Score: 700}))
+	v, ok := d.Pop(TestKey{Name: "bravo", Score: 300})
+	assert.True(t, ok)
+	assert.Equal(t, 2, v)
+	_, ok = d.Pop(TestKey{Name: "bravo", Score: 300})
+	assert.False(t, ok)
+	assert.Equal(t, map[TestKey]int{{Name: "alpha", Score: 100}: 1}, d.Map())
+
+	d.Clear()
+	assert.False(t, d.Contains(TestKey{Name: "alpha", Score: 100}))
+	assert.Empty(t, d.Map())
+
+	// an untouched dictionary has a nil map, like Dictionary
+	var plain container.Dictionary[string, int]
+	assert.Nil(t, plain.Map())
+	assert.Nil(t, container.NewShardedDictionary[string, int](8, nil).Map())
+}
+
+func TestShardedDictionaryDefaultHash(t *testing.T) {
+	type key struct {
+		Name  string
+		Ratio float64
+		Ptr   *int
+		Any   any
+	}
+	one := new(int)
+	d := container.NewShardedDictionary[key, int](64, nil)
+	for kk := 0; kk < 100; kk++ {
+		d.Set(key{strconv.Itoa(kk), float64(kk), one, kk}, kk)
+	}
+	for kk := 0; kk < 100; kk++ {
+		assert.Equal(t, kk, d.Get(key{strconv.Itoa(kk), float64(kk), one, kk}))
+	}
+	assert.False(t, d.Contains(key{"1", 1, new(int), 1}))
+
+	// -0 and 0 are the same key
+	d.Set(key{Name: "zero"}, -1)
+	assert.Equal(t, -1, d.Get(key{Name: "zero", Ratio: math.Copysign(0, -1)}))
+}
+
+func TestShardedDictionaryBlankFields(t *testing.T) {
+	type key struct {
+		ID int
+		_  int
+	}
+	// blank fields can't be set, so write them through a twin type
+	type twin struct {
+		ID, Blank int
+	}
+	withBlank := func(id, blank int) key {
+		return *(*key)(unsafe.Pointer(&twin{id, blank}))
+	}
+	d := container.NewShardedDictionary[key, int](64, nil)
+	for kk := 0; kk < 100; kk++ {
+		d.Set(withBlank(kk, 1), kk)
+	}
+	for kk := 0; kk < 100; kk++ {
+		k := withBlank(kk, 2)
+		assert.True(t, withBlank(kk, 1) == k)
+		assert.Equal(t, kk, d.Get(k))
+	}
+	assert.Len(t, d.Map(), 100)
+}
+
+func TestShardedDictionaryEach(t *testing.T) {
+	// a constant hasher puts everything in one shard
+	for _, hash := range []func(int) uint64{nil, func(int) uint64 { return 7 }} {
+		d := container.NewShardedDictionary[int, int](5, hash)
+		want := map[int]int{}
+		for i := 0; i < 1000; i++ {
+			d.Set(i, i*i)
+			want[i] = i * i
+		}
+		got := map[int]int{}
+		d.Each(func(k, v int) bool {
+			got[k] = v
+			return true
+		})
+		assert.Equal(t, want, got)
+
+		count := 0
+		d.Each(func(k, v int) bool {
+			count++
+			return count < 10
+		})
+		assert.Equal(t, 10, count)
+	}
+
+	// setting a key doesn't affect the shard being iterated
+	d := container.NewShardedDictionary[int, int](1, nil)
+	d.Set(1, 1)
+	d.Set(2, 2)
+	count := 0
+	d.Each(func(k, v int) bool {
+		d.Set(k+10, v)
+		count++
+		return true
+	})
+	assert.Equal(t, 2, count)
+	assert.Len(t, d.Map(), 4)
+}
+
+func TestShardedDictionaryConcurrent(t *testing.T) {
+	d := container.NewShardedDictionary[int, int](8, nil)
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				d.Set(g*1000+i, i)
+				d.Get(i)
+				if i%2 == 1 {
+					d.Remove(g*1000 + i)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	assert.Len(t, d.Map(), 4000)
+}
+
+func TestShardedDictionaryAtomicOperations(t *testing.T) {
+	testAtomicOperations(t, container.NewShardedDictionary[string, int](4, nil))
+	testConcurrentCompute(t, container.NewShardedDictionary[string, int](4, nil))
+}
+
+const dictionaryBenchKeys = 1 << 12
+
+func dictionaryBenchKey(i int) string {
+	return strconv.Itoa(i & (dictionaryBenchKeys - 1))
+}
+
+// benchmarkDictionary runs a parallel load with one write every writeEvery
+// operations.
+func benchmarkDictionary(b *testing.B, writeEvery int, get func(k string) int, set func(k string, v int)) {
+	for i := 0; i < dictionaryBenchKeys; i++ {
+		set(dictionaryBenchKey(i), i)
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			k := dictionaryBenchKey(i)
+			if i%writeEvery == 0 {
+				set(k, i)
+			} else {
+				get(k)
+			}
+			i += 7
+		}
+	})
+}
+
+func benchmarkDictionaries(b *testing.B, writeEvery int) {
+	b.Run("Dictionary", func(b *testing.B) {
+		var d container.Dictionary[string, int]
+		benchmarkDictionary(b, writeEvery, d.Get, d.Set)
+	})
+	b.Run("ShardedDictionary", func(b *testing.B) {
+		d := container.NewShardedDictionary[string, int](0, nil)
+		benchmarkDictionary(b, writeEvery, d.Get, d.Set)
+	})
+	b.Run("sync.Map", func(b *testing.B) {
+		var d sync.Map
+		benchmarkDictionary(b, writeEvery, func(k string) int {
+			v, _ := d.Load(k)
+			n, _ := v.(int)
+			return n
+		}, func(k string, v int) {
+			d.Store(k, v)
+		})
+	})
+}
+
+func BenchmarkDictionaryReadHeavy(b *testing.B) {
+	benchmarkDictionaries(b, 16)
+}
+
+func BenchmarkDictionaryWriteHeavy(b *testing.B) {
+	benchmarkDictionaries(b, 2)
+}
+
+func TestShardedDictionaryIterators(t *testing.T) {
+	d := container.NewShardedDictionary[int, int](4, nil)
+	want := map[int]int{}
+	for i := 0; i < 100; i++ {
+		d.Set(i, -i)
+		want[i] = -i
+	}
+	assert.Equal(t, want, maps.Collect(d.All()))
+	assert.Equal(t, slices.Sorted(maps.Keys(want)), slices.Sorted(d.Keys()))
+	assert.Equal(t, slices.Sorted(maps.Values(want)), slices.Sorted(d.Values()))
+	for k := range d.Keys() {
+		d.Remove(k)
+	}
+	assert.Empty(t, d.Map())
+}
@@ -1,6 +1,7 @@
 package container
 
 import (
+	"iter"
 	"sort"
 	"sync"
 
@@ -134,6 +135,67 @@ func (m *SortedDictionary[KT, VT]) Each(fn func(KT, VT) bool) {
 	}
 }
 
+// snapshot returns a copy of the items.
+func (m *SortedDictionary[KT, VT]) snapshot() []sortedDictionaryItem[KT, VT] {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	items := make([]sortedDictionaryItem[KT, VT], len(m.items))
+	copy(items, m.items)
+	return items
+}
+
+// All returns an iterator over the key=value pairs in ascending key order.
+// Like Each, it iterates a copy of the items taken when the iteration
+// starts, so the loop may modify the dictionary.
+func (m *SortedDictionary[KT, VT]) All() iter.Seq2[KT, VT] {
+	return func(yield func(KT, VT) bool) {
+		for _, item := range m.snapshot() {
+			if !yield(item.key, item.val) {
+				return
+			}
+		}
+	}
+}
+
+// Backward returns an iterator over the key=value pairs in descending key
+// order. Like All, it iterates a copy of the items.
+func (m *SortedDictionary[KT, VT]) Backward() iter.Seq2[KT, VT] {
+	return func(yield func(KT, VT) bool) {
+		items := m.snapshot()
+		for i := len(items) - 1; i >= 0; i-- {
+			if !yield(items[i].key, items[i].val) {
+				return
+			}
+		}
+	}
+}
+
+// KeysSeq returns an iterator over the keys in ascending order. Like All,
+// it iterates a copy of the items. It is named apart from Keys, which
+// returns a slice.
+func (m *SortedDictionary[KT, VT]) KeysSeq() iter.Seq[KT] {
+	return func(yield func(KT) bool) {
+		for k := range m.All() {
+			if !yield(k) {
+				return
+			}
+		}
+	}
+}
+
+// ValuesSeq returns an iterator over the values in ascending key order.
+// Like All, it iterates a copy of the items. It is named apart from
+// Values, which returns a slice.
+func (m *SortedDictionary[KT, VT]) ValuesSeq() iter.Seq[VT] {
+	return func(yield func(VT) bool) {
+		for _, v := range m.All() {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
 // Values returns a slice copy of the values.
 func (m *SortedDictionary[KT, VT]) Values() []VT {
 	m.lock.RLock()
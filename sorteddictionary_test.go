@@ -1,6 +1,8 @@
 package container_test
 
 import (
+	"maps"
+	"slices"
 	"testing"
 
 	"github.com/gabstv/container"
@@ -20,3 +22,33 @@ func TestSortedDictionary(t *testing.T) {
 	d.Set(200, "four")
 	assert.Equal(t, "four", d.Get(200))
 }
+
+func TestSortedDictionaryIterators(t *testing.T) {
+	var d container.SortedDictionary[string, int]
+	assert.Empty(t, maps.Collect(d.All()))
+	d.Set("b", 2)
+	d.Set("c", 3)
+	d.Set("a", 1)
+
+	keys, values := []string{}, []int{}
+	for k, v := range d.All() {
+		keys = append(keys, k)
+		values = append(values, v)
+		// the loop iterates a snapshot
+		d.Remove(k)
+		d.Set(k+k, v)
+	}
+	assert.Equal(t, []string{"a", "b", "c"}, keys)
+	assert.Equal(t, []int{1, 2, 3}, values)
+	assert.Equal(t, []string{"aa", "bb", "cc"}, slices.Collect(d.KeysSeq()))
+	assert.Equal(t, []int{1, 2, 3}, slices.Collect(d.ValuesSeq()))
+
+	keys = keys[:0]
+	for k, v := range d.Backward() {
+		keys = append(keys, k)
+		if v == 2 {
+			break
+		}
+	}
+	assert.Equal(t, []string{"cc", "bb"}, keys)
+}
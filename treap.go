@@ -42,13 +42,19 @@ type CompareFn[T any] func(left, right T) int
 // have lower priority than the parent)
 //
 // This provides the basis for efficient immutable ordered Set
-// operations.  See the ordered map example for how this can be used
+// operations.  See PersistentMap for how this can be used
 // as an ordered map
 //
 // Much of this is based on "Fast Set Operations Using Treaps"
 // by Guy E Blelloch and Margaret Reid-Miller:
 // https://www.cs.cmu.edu/~scandal/papers/treaps-spaa98.pdf
 //
+// Treap has unexported fields, so it can't be built with positional
+// literals like &Treap[T]{v, p, l, r} anymore; name the fields instead.
+// Nodes built by hand don't know their subtree size, which makes Len,
+// At and Rank walk the whole subtree. Call ComputeSizes on the root once
+// the treap is built to get them back to O(1) and O(log n).
+//
 // Benchmark
 //
 // The most interesting benchmark is the performance of insert where a
@@ -78,14 +84,110 @@ type Treap[T any] struct {
 	Value       T
 	Priority    int
 	Left, Right *Treap[T]
+
+	// size is the number of values in the subtree rooted at this node.
+	// Nodes built by hand leave it at zero until ComputeSizes records it.
+	size int
+
+	// edit is the owner token of the transient that created this node, see
+	// TransientTreap. Nodes are only modified in place by their owner.
+	edit *treapEdit
+}
+
+// treapUpdate recomputes the data a node derives from its children, on top
+// of its size, like the summaries of AugmentedTreap. The internal versions
+// of split, join and the set operations take one and run it on every node
+// they create or relink, after its children. It is nil for plain treaps.
+type treapUpdate[T any] func(n *Treap[T])
+
+// fix recomputes the subtree size of n from its children and runs u.
+func (n *Treap[T]) fix(u treapUpdate[T]) *Treap[T] {
+	n.size = 1 + n.Left.Len() + n.Right.Len()
+	if u != nil {
+		u(n)
+	}
+	return n
+}
+
+// newTreap returns a node with its subtree size computed from the children.
+func newTreap[T any](v T, priority int, left, right *Treap[T], u treapUpdate[T]) *Treap[T] {
+	return (&Treap[T]{Value: v, Priority: priority, Left: left, Right: right}).fix(u)
+}
+
+// fixPath fixes the nodes created top-down by split, SplitAt and join.
+// Children always come after their parents in path.
+func fixPath[T any](path []*Treap[T], u treapUpdate[T]) {
+	for i := len(path) - 1; i >= 0; i-- {
+		path[i].fix(u)
+	}
+}
+
+// Len returns the number of values in the treap. It is O(1) for treaps built
+// by the methods of this package. Nodes built by hand are counted on demand,
+// see ComputeSizes.
+func (n *Treap[T]) Len() int {
+	if n == nil {
+		return 0
+	}
+	if n.size == 0 {
+		return 1 + n.Left.Len() + n.Right.Len()
+	}
+	return n.size
+}
+
+// ComputeSizes records the subtree sizes of a treap built by hand, so that
+// Len, At, Rank and the set operations don't count its nodes over and over.
+// It modifies the nodes, so call it before sharing the treap. It returns n.
+func (n *Treap[T]) ComputeSizes() *Treap[T] {
+	if n != nil && n.size == 0 {
+		n.Left.ComputeSizes()
+		n.Right.ComputeSizes()
+		n.size = 1 + n.Left.Len() + n.Right.Len()
+	}
+	return n
+}
+
+// At returns the node holding the k-th smallest value (zero based), or nil
+// if k is out of range.
+func (n *Treap[T]) At(k int) *Treap[T] {
+	for n != nil {
+		left := n.Left.Len()
+		switch {
+		case k < left:
+			n = n.Left
+		case k == left:
+			return n
+		default:
+			k -= left + 1
+			n = n.Right
+		}
+	}
+	return nil
+}
+
+// Rank returns the number of values in the treap that compare less-than v.
+// When v is present, this is its zero based position.
+func (n *Treap[T]) Rank(v T, c CompareFn[T]) int {
+	rank := 0
+	for n != nil {
+		diff := c(n.Value, v)
+		switch {
+		case diff < 0:
+			rank += n.Left.Len() + 1
+			n = n.Right
+		case diff > 0:
+			n = n.Left
+		default:
+			return rank + n.Left.Len()
+		}
+	}
+	return rank
 }
 
 // ForEach does inorder traversal of the treap
 func (n *Treap[T]) ForEach(fn func(v T)) {
-	if n != nil {
-		n.Left.ForEach(fn)
-		fn(n.Value)
-		n.Right.ForEach(fn)
+	for it := n.Iterator(); it.Next(); {
+		fn(it.current.Value)
 	}
 }
 
@@ -111,6 +213,10 @@ func (n *Treap[T]) Find(v T, c CompareFn[T]) *Treap[T] {
 // field controls whether the union keeps the original value or
 // whether it is updated based on value in the "other" arg
 func (n *Treap[T]) Union(other *Treap[T], c CompareFn[T], overwrite bool) *Treap[T] {
+	return n.union(other, c, overwrite, nil)
+}
+
+func (n *Treap[T]) union(other *Treap[T], c CompareFn[T], overwrite bool, u treapUpdate[T]) *Treap[T] {
 	if n == nil {
 		return other
 	}
@@ -122,29 +228,36 @@ func (n *Treap[T]) Union(other *Treap[T], c CompareFn[T], overwrite bool) *Treap
 		other, n, overwrite = n, other, !overwrite
 	}
 
-	left, dupe, right := other.Split(n.Value, c)
+	left, dupe, right := other.split(n.Value, c, u)
 	value := n.Value
 	if overwrite && dupe != nil {
 		value = dupe.Value
 	}
-	left = n.Left.Union(left, c, overwrite)
-	right = n.Right.Union(right, c, overwrite)
-	return &Treap[T]{value, n.Priority, left, right}
+	left = n.Left.union(left, c, overwrite, u)
+	right = n.Right.union(right, c, overwrite, u)
+	return newTreap(value, n.Priority, left, right, u)
 }
 
 // Split splits the treap into all nodes that compare less-than, equal
 // and greater-than the provided value.  The resulting values are
 // properly formed treaps or nil if they contain no values.
 func (n *Treap[T]) Split(v T, c CompareFn[T]) (left, mid, right *Treap[T]) {
+	return n.split(v, c, nil)
+}
+
+func (n *Treap[T]) split(v T, c CompareFn[T], u treapUpdate[T]) (left, mid, right *Treap[T]) {
+	var path []*Treap[T]
 	leftp, rightp := &left, &right
 	for {
 		if n == nil {
 			*leftp = nil
 			*rightp = nil
+			fixPath(path, u)
 			return left, nil, right
 		}
 
-		root := &Treap[T]{n.Value, n.Priority, nil, nil}
+		root := &Treap[T]{Value: n.Value, Priority: n.Priority}
+		path = append(path, root)
 		diff := c(n.Value, v)
 		switch {
 		case diff < 0:
@@ -160,11 +273,42 @@ func (n *Treap[T]) Split(v T, c CompareFn[T]) (left, mid, right *Treap[T]) {
 		default:
 			*leftp = n.Left
 			*rightp = n.Right
+			fixPath(path, u)
 			return left, root, right
 		}
 	}
 }
 
+// SplitAt splits the treap into the k smallest values and the remaining
+// ones. k is clamped to the [0, Len()] range.
+func (n *Treap[T]) SplitAt(k int) (left, right *Treap[T]) {
+	var path []*Treap[T]
+	leftp, rightp := &left, &right
+	for {
+		if n == nil {
+			*leftp = nil
+			*rightp = nil
+			fixPath(path, nil)
+			return left, right
+		}
+
+		root := &Treap[T]{Value: n.Value, Priority: n.Priority}
+		path = append(path, root)
+		if size := n.Left.Len(); size < k {
+			*leftp = root
+			root.Left = n.Left
+			leftp = &root.Right
+			k -= size + 1
+			n = n.Right
+		} else {
+			*rightp = root
+			root.Right = n.Right
+			rightp = &root.Left
+			n = n.Left
+		}
+	}
+}
+
 // Intersection returns a new treap with all the common values in the
 // two treaps.
 //
@@ -173,54 +317,53 @@ func (n *Treap[T]) Split(v T, c CompareFn[T]) (left, mid, right *Treap[T]) {
 //   by Guy E Blelloch and Margaret Reid-Miller.
 //
 // The algorithm is a very slight variation on that.
+//
+// Every node of the result is a fresh copy, so it runs as a transient
+// owned by the call, which lets the joins work in place. The result is
+// disowned before it is returned.
 func (n *Treap[T]) Intersection(other *Treap[T], c CompareFn[T]) *Treap[T] {
-	if n == nil || other == nil {
-		return nil
-	}
-
-	if n.Priority < other.Priority {
-		n, other = other, n
-	}
-
-	left, found, right := other.Split(n.Value, c)
-	left = n.Left.Intersection(left, c)
-	right = n.Right.Intersection(right, c)
-
-	if found == nil {
-		// TODO: use a destructive join as both left/right are copies
-		return left.join(right)
-	}
-
-	return &Treap[T]{n.Value, n.Priority, left, right}
+	edit := new(treapEdit)
+	return n.intersectionT(other, c, edit, nil).disown(edit)
 }
 
 // Delete removes a node if it exists.
 func (n *Treap[T]) Delete(v T, c CompareFn[T]) *Treap[T] {
 	left, _, right := n.Split(v, c)
-	return left.join(right)
+	return left.join(right, nil)
 }
 
 // Diff finds all elements of current treap which aren't present in
 // the other heap
 func (n *Treap[T]) Diff(other *Treap[T], c CompareFn[T]) *Treap[T] {
+	return n.diff(other, c, nil)
+}
+
+func (n *Treap[T]) diff(other *Treap[T], c CompareFn[T], u treapUpdate[T]) *Treap[T] {
 	if n == nil || other == nil {
 		return n
 	}
 
-	// TODO -- use  count
+	var result *Treap[T]
 	if n.Priority >= other.Priority {
-		left, dupe, right := other.Split(n.Value, c)
-		left, right = n.Left.Diff(left, c), n.Right.Diff(right, c)
+		left, dupe, right := other.split(n.Value, c, u)
+		left, right = n.Left.diff(left, c, u), n.Right.diff(right, c, u)
 		if dupe != nil {
-			return left.join(right)
+			return left.join(right, u)
 		}
-		return &Treap[T]{n.Value, n.Priority, left, right}
+		result = newTreap(n.Value, n.Priority, left, right, u)
+	} else {
+		left, _, right := n.split(other.Value, c, u)
+		left = left.diff(other.Left, c, u)
+		right = right.diff(other.Right, c, u)
+		result = left.join(right, u)
 	}
 
-	left, _, right := n.Split(other.Value, c)
-	left = left.Diff(other.Left, c)
-	right = right.Diff(other.Right, c)
-	return left.join(right)
+	// the result is a subset of n, so the same count means nothing was
+	// removed and the original subtree can be shared instead.
+	if result.Len() == n.Len() {
+		return n
+	}
+	return result
 }
 
 // see https://www.cs.cmu.edu/~scandal/papers/treaps-spaa98.pdf
@@ -229,31 +372,37 @@ func (n *Treap[T]) Diff(other *Treap[T], c CompareFn[T]) *Treap[T] {
 //
 // The algorithm is a very slight variation on that provided there.
 //
-// Note that all nodes in n have priority <= that of "other" for
-// this call to work correctly.  It traverses  the right spine of n
-// and left-spine of other, merging things along the way
+// Note that all values in n must compare less-than those in "other"
+// for this call to work correctly.  It traverses  the right spine of n
+// and left-spine of other, merging things along the way, always keeping
+// the node with the higher priority on top
 //
 // The algorithm is not that  different from zipping up a spine
-func (n *Treap[T]) join(other *Treap[T]) *Treap[T] {
+func (n *Treap[T]) join(other *Treap[T], u treapUpdate[T]) *Treap[T] {
+	var path []*Treap[T]
 	var result *Treap[T]
 	resultp := &result
 	for {
 		if n == nil {
 			*resultp = other
+			fixPath(path, u)
 			return result
 		}
 		if other == nil {
 			*resultp = n
+			fixPath(path, u)
 			return result
 		}
 
-		if n.Priority <= other.Priority {
-			root := &Treap[T]{n.Value, n.Priority, n.Left, nil}
+		if n.Priority >= other.Priority {
+			root := &Treap[T]{Value: n.Value, Priority: n.Priority, Left: n.Left}
+			path = append(path, root)
 			*resultp = root
 			resultp = &root.Right
 			n = n.Right
 		} else {
-			root := &Treap[T]{other.Value, other.Priority, nil, other.Right}
+			root := &Treap[T]{Value: other.Value, Priority: other.Priority, Right: other.Right}
+			path = append(path, root)
 			*resultp = root
 			resultp = &root.Left
 			other = other.Left
@@ -261,14 +410,25 @@ func (n *Treap[T]) join(other *Treap[T]) *Treap[T] {
 	}
 }
 
-// Union combines two or more treaps. In case of duplicates, the overwrite field
-// controls whether the union keeps the original value or whether it is updated
-// based on value in the items
+// TreapUnion builds a treap holding the given items, all with the same
+// priority. In case of duplicates, the first item is kept.
 //
+// Deprecated: a single priority degrades the treap into a list for sorted
+// items. Use TreapUnionFunc.
 func TreapUnion[T any](comparer CompareFn[T], priority int, items ...T) *Treap[T] {
+	return TreapUnionFunc(comparer, FixedPriority[T](priority), items...)
+}
+
+// TreapUnionFunc builds a treap holding the given items, using p to pick the
+// priority of each of them. A nil p draws random priorities, see
+// RandomPriority. In case of duplicates, the first item is kept.
+func TreapUnionFunc[T any](comparer CompareFn[T], p PriorityFn[T], items ...T) *Treap[T] {
+	if p == nil {
+		p = RandomPriority[T]()
+	}
 	var t *Treap[T]
 	for _, elt := range items {
-		t = t.Union(&Treap[T]{elt, priority, nil, nil}, comparer, false)
+		t = t.Union(&Treap[T]{Value: elt, Priority: p(elt), size: 1}, comparer, false)
 	}
 	return t
 }
@@ -8,6 +8,7 @@ import (
 	"math/rand"
 	"reflect"
 	"sort"
+	"sync"
 	"testing"
 
 	"github.com/gabstv/container"
@@ -102,6 +103,81 @@ func TestDiff(t *testing.T) {
 	}
 }
 
+func TestOrderStatistics(t *testing.T) {
+	rand.Seed(42)
+
+	input := rand.Perm(1000)
+	x := ToTreap(input)
+	if x.Len() != 1000 {
+		t.Fatal("Len diverged", x.Len())
+	}
+
+	for kk := 0; kk < 1000; kk++ {
+		if n := x.At(kk); n == nil || n.Value != kk {
+			t.Fatal("At diverged", kk)
+		}
+		if r := x.Rank(kk, intComparer[int]); r != kk {
+			t.Fatal("Rank diverged", kk, r)
+		}
+	}
+	if x.At(-1) != nil || x.At(1000) != nil {
+		t.Fatal("At out of range")
+	}
+	if r := x.Rank(5000, intComparer[int]); r != 1000 {
+		t.Fatal("Rank of missing value diverged", r)
+	}
+
+	left, right := x.SplitAt(300)
+	if left.Len() != 300 || right.Len() != 700 {
+		t.Fatal("SplitAt sizes diverged", left.Len(), right.Len())
+	}
+	expected := make([]int, 1000)
+	for kk := range expected {
+		expected[kk] = kk
+	}
+	if !reflect.DeepEqual(ToArray(left), expected[:300]) || !reflect.DeepEqual(ToArray(right), expected[300:]) {
+		t.Fatal("SplitAt diverged")
+	}
+
+	deleted := x.Delete(500, intComparer[int])
+	if deleted.Len() != 999 || x.Len() != 1000 {
+		t.Fatal("Delete sizes diverged")
+	}
+	if deleted.At(500).Value != 501 {
+		t.Fatal("Delete diverged")
+	}
+
+	// hand built nodes are counted on demand, which only reads them
+	manual := &container.Treap[int]{Value: 2, Priority: 10,
+		Left:  &container.Treap[int]{Value: 1, Priority: 5},
+		Right: &container.Treap[int]{Value: 3, Priority: 5},
+	}
+	var wg sync.WaitGroup
+	for range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if manual.Len() != 3 || manual.At(2).Value != 3 {
+				t.Error("Len of hand built treap diverged")
+			}
+		}()
+	}
+	wg.Wait()
+	if manual.ComputeSizes() != manual || manual.Validate(intComparer[int]) != nil || manual.Len() != 3 {
+		t.Fatal("ComputeSizes of hand built treap diverged")
+	}
+	if manual.Diff(ToTreap([]int{10}), intComparer[int]) != manual {
+		t.Fatal("Diff of hand built treap diverged")
+	}
+}
+
+func TestDiffSharesUnchangedSubtrees(t *testing.T) {
+	x := ToTreap([]int{1, 2, 3, 4, 5})
+	if x.Diff(ToTreap([]int{10, 20}), intComparer[int]) != x {
+		t.Fatal("Diff should return the receiver when nothing is removed")
+	}
+}
+
 func BenchmarkInsert(b *testing.B) {
 	rand.Seed(42)
 
@@ -266,7 +342,7 @@ func ToTreap[T constraints.Integer](v []T) *container.Treap[T] {
 	var t *container.Treap[T]
 	for _, elt := range v {
 		priority := rand.Intn(10000000)
-		t = t.Union(&container.Treap[T]{elt, priority, nil, nil}, intComparer[T], false)
+		t = t.Union(&container.Treap[T]{Value: elt, Priority: priority}, intComparer[T], false)
 	}
 	return t
 }
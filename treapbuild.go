@@ -0,0 +1,73 @@
+package container
+
+import "sort"
+
+// TreapFromSorted builds a treap from values that are sorted in ascending
+// order and contain no duplicates. It runs in linear time, which is much
+// cheaper than inserting the values one by one. The priority of each node
+// is given by priority, or is random if priority is nil.
+//
+// The result is undefined if items is not sorted or has duplicates.
+func TreapFromSorted[T any](items []T, priority PriorityFn[T]) *Treap[T] {
+	if priority == nil {
+		priority = RandomPriority[T]()
+	}
+	var b treapBuilder[T]
+	for _, v := range items {
+		b.push(v, priority(v))
+	}
+	return b.finish()
+}
+
+// treapBuilder builds a treap from values pushed in ascending order.
+type treapBuilder[T any] struct {
+	// spine holds the right spine of the treap built so far. Nodes are
+	// only popped once their subtree is complete, which is when their
+	// size can be computed.
+	spine []*Treap[T]
+}
+
+func (b *treapBuilder[T]) push(v T, priority int) {
+	n := &Treap[T]{Value: v, Priority: priority}
+	var last *Treap[T]
+	for len(b.spine) > 0 && b.spine[len(b.spine)-1].Priority < n.Priority {
+		last = b.spine[len(b.spine)-1]
+		last.fix(nil)
+		b.spine = b.spine[:len(b.spine)-1]
+	}
+	n.Left = last
+	if len(b.spine) > 0 {
+		b.spine[len(b.spine)-1].Right = n
+	}
+	b.spine = append(b.spine, n)
+}
+
+func (b *treapBuilder[T]) finish() *Treap[T] {
+	if len(b.spine) == 0 {
+		return nil
+	}
+	for i := len(b.spine) - 1; i >= 0; i-- {
+		b.spine[i].fix(nil)
+	}
+	root := b.spine[0]
+	b.spine = b.spine[:0]
+	return root
+}
+
+// TreapFromSlice builds a treap from values in any order. Duplicates are
+// dropped, keeping the first occurrence. items is not modified. See
+// TreapFromSorted for the meaning of priority.
+func TreapFromSlice[T any](items []T, c CompareFn[T], priority PriorityFn[T]) *Treap[T] {
+	sorted := make([]T, len(items))
+	copy(sorted, items)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return c(sorted[i], sorted[j]) < 0
+	})
+	unique := sorted[:0]
+	for i, v := range sorted {
+		if i == 0 || c(unique[len(unique)-1], v) != 0 {
+			unique = append(unique, v)
+		}
+	}
+	return TreapFromSorted(unique, priority)
+}
@@ -0,0 +1,84 @@
+package container_test
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/gabstv/container"
+)
+
+func TestTreapFromSorted(t *testing.T) {
+	rand.Seed(42)
+
+	input := rangeInts(0, 10000)
+	x := container.TreapFromSorted(input, nil)
+	if !reflect.DeepEqual(ToArray(x), input) {
+		t.Fatal("TreapFromSorted diverged")
+	}
+	if x.Len() != len(input) || x.At(1234).Value != 1234 {
+		t.Fatal("TreapFromSorted sizes diverged")
+	}
+	if !isHeapOrdered(x) {
+		t.Fatal("TreapFromSorted is not heap ordered")
+	}
+
+	fixed := container.TreapFromSorted([]int{1, 2, 3}, func(v int) int {
+		return v
+	})
+	if fixed.Value != 3 || fixed.Left.Value != 2 || fixed.Left.Left.Value != 1 {
+		t.Fatal("TreapFromSorted ignored the priorities")
+	}
+
+	if container.TreapFromSorted([]int{}, nil) != nil {
+		t.Fatal("TreapFromSorted of an empty slice should be nil")
+	}
+}
+
+func TestTreapFromSlice(t *testing.T) {
+	rand.Seed(42)
+
+	input := []int{}
+	for kk := 0; kk < 5000; kk++ {
+		input = append(input, rand.Intn(1000))
+	}
+	original := append([]int(nil), input...)
+
+	x := container.TreapFromSlice(input, intComparer[int], nil)
+	if !reflect.DeepEqual(ToArray(x), ToArray(ToTreap(input))) {
+		t.Fatal("TreapFromSlice diverged")
+	}
+	if !reflect.DeepEqual(input, original) {
+		t.Fatal("TreapFromSlice modified its input")
+	}
+	if !isHeapOrdered(x) {
+		t.Fatal("TreapFromSlice is not heap ordered")
+	}
+}
+
+func BenchmarkTreapFromSorted(b *testing.B) {
+	input := rangeInts(0, 100000)
+	for kk := 0; kk < b.N; kk++ {
+		container.TreapFromSorted(input, nil)
+	}
+}
+
+func BenchmarkTreapFromSortedUnion(b *testing.B) {
+	input := rangeInts(0, 100000)
+	for kk := 0; kk < b.N; kk++ {
+		ToTreap(input)
+	}
+}
+
+func isHeapOrdered[T any](n *container.Treap[T]) bool {
+	if n == nil {
+		return true
+	}
+	if n.Left != nil && n.Left.Priority > n.Priority {
+		return false
+	}
+	if n.Right != nil && n.Right.Priority > n.Priority {
+		return false
+	}
+	return isHeapOrdered(n.Left) && isHeapOrdered(n.Right)
+}
@@ -0,0 +1,165 @@
+package container
+
+import "iter"
+
+// ChangeKind tells how a value changed between two versions of a treap.
+type ChangeKind int
+
+const (
+	ChangeAdded ChangeKind = iota + 1
+	ChangeRemoved
+	ChangeUpdated
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case ChangeAdded:
+		return "added"
+	case ChangeRemoved:
+		return "removed"
+	case ChangeUpdated:
+		return "updated"
+	}
+	return "unknown"
+}
+
+// TreapChange is a value that differs between two versions of a treap.
+// Old is the zero value for added values and New is the zero value for
+// removed ones.
+type TreapChange[T any] struct {
+	Kind     ChangeKind
+	Old, New T
+}
+
+// TreapChanges returns an iterator over the differences between two
+// versions of a treap, in ascending order. Values that compare equal with
+// c but for which equal returns false are reported as updated; if equal is
+// nil, values that compare equal are considered unchanged.
+//
+// Subtrees shared by both versions are skipped without being visited, so
+// comparing a treap with one derived from it by a few edits only costs
+// time proportional to the edits and the depth of the treap.
+func TreapChanges[T any](old, new *Treap[T], c CompareFn[T], equal func(a, b T) bool) iter.Seq[TreapChange[T]] {
+	return func(yield func(TreapChange[T]) bool) {
+		treapChanges(old, new, c, equal, yield)
+	}
+}
+
+func treapChanges[T any](old, new *Treap[T], c CompareFn[T], equal func(a, b T) bool, yield func(TreapChange[T]) bool) bool {
+	if old == new {
+		return true
+	}
+	if old == nil {
+		for v := range new.All() {
+			if !yield(TreapChange[T]{Kind: ChangeAdded, New: v}) {
+				return false
+			}
+		}
+		return true
+	}
+	if new == nil {
+		for v := range old.All() {
+			if !yield(TreapChange[T]{Kind: ChangeRemoved, Old: v}) {
+				return false
+			}
+		}
+		return true
+	}
+
+	// Split the version with the lower priority root around the other
+	// root. Split keeps the subtrees hanging off its path, so shared
+	// subtrees are still pointer-identical in the recursive calls.
+	if old.Priority >= new.Priority {
+		left, mid, right := new.Split(old.Value, c)
+		if !treapChanges(old.Left, left, c, equal, yield) {
+			return false
+		}
+		switch {
+		case mid == nil:
+			if !yield(TreapChange[T]{Kind: ChangeRemoved, Old: old.Value}) {
+				return false
+			}
+		case equal != nil && !equal(old.Value, mid.Value):
+			if !yield(TreapChange[T]{Kind: ChangeUpdated, Old: old.Value, New: mid.Value}) {
+				return false
+			}
+		}
+		return treapChanges(old.Right, right, c, equal, yield)
+	}
+
+	left, mid, right := old.Split(new.Value, c)
+	if !treapChanges(left, new.Left, c, equal, yield) {
+		return false
+	}
+	switch {
+	case mid == nil:
+		if !yield(TreapChange[T]{Kind: ChangeAdded, New: new.Value}) {
+			return false
+		}
+	case equal != nil && !equal(mid.Value, new.Value):
+		if !yield(TreapChange[T]{Kind: ChangeUpdated, Old: mid.Value, New: new.Value}) {
+			return false
+		}
+	}
+	return treapChanges(right, new.Right, c, equal, yield)
+}
+
+// TreapMerge3 merges two versions of a treap, ours and theirs, derived from
+// a common base. The result starts from ours and gets the changes made by
+// theirs since base. When both sides changed the same value differently,
+// resolve is called with both changes and returns the value to keep, or
+// false to remove it. See TreapChanges for the meaning of equal, and
+// Transient for the meaning of p.
+func TreapMerge3[T any](base, ours, theirs *Treap[T], c CompareFn[T], equal func(a, b T) bool, p PriorityFn[T], resolve func(ours, theirs TreapChange[T]) (T, bool)) *Treap[T] {
+	var mine []TreapChange[T]
+	for ch := range TreapChanges(base, ours, c, equal) {
+		mine = append(mine, ch)
+	}
+
+	t := ours.Transient(c, p)
+	for other := range TreapChanges(base, theirs, c, equal) {
+		key := changeKey(other)
+		for len(mine) > 0 && c(changeKey(mine[0]), key) < 0 {
+			mine = mine[1:]
+		}
+		if len(mine) == 0 || c(changeKey(mine[0]), key) != 0 {
+			t.apply(other)
+			continue
+		}
+		own := mine[0]
+		mine = mine[1:]
+		if sameChange(own, other, equal) {
+			continue
+		}
+		if v, keep := resolve(own, other); keep {
+			t.Insert(v)
+		} else {
+			t.Delete(key)
+		}
+	}
+	return t.Persistent()
+}
+
+func (t *TransientTreap[T]) apply(ch TreapChange[T]) {
+	if ch.Kind == ChangeRemoved {
+		t.Delete(ch.Old)
+	} else {
+		t.Insert(ch.New)
+	}
+}
+
+// changeKey returns a value of the change to compare it against others.
+func changeKey[T any](ch TreapChange[T]) T {
+	if ch.Kind == ChangeRemoved {
+		return ch.Old
+	}
+	return ch.New
+}
+
+// sameChange reports whether both changes lead to the same value.
+func sameChange[T any](a, b TreapChange[T], equal func(a, b T) bool) bool {
+	if a.Kind == ChangeRemoved || b.Kind == ChangeRemoved {
+		return a.Kind == b.Kind
+	}
+	return equal == nil || equal(a.New, b.New)
+}
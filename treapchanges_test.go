@@ -0,0 +1,128 @@
+package container_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gabstv/container"
+	"github.com/stretchr/testify/assert"
+)
+
+type setting struct {
+	Key, Value string
+}
+
+func compareSettings(left, right setting) int {
+	return strings.Compare(left.Key, right.Key)
+}
+
+func equalSettings(left, right setting) bool {
+	return left == right
+}
+
+func settings(kv ...string) *container.Treap[setting] {
+	items := []setting{}
+	for i := 0; i < len(kv); i += 2 {
+		items = append(items, setting{kv[i], kv[i+1]})
+	}
+	return container.TreapFromSlice(items, compareSettings, nil)
+}
+
+func collectChanges[T any](old, new *container.Treap[T], c container.CompareFn[T], equal func(a, b T) bool) []container.TreapChange[T] {
+	out := []container.TreapChange[T]{}
+	for ch := range container.TreapChanges(old, new, c, equal) {
+		out = append(out, ch)
+	}
+	return out
+}
+
+func TestTreapChanges(t *testing.T) {
+	v1 := settings("a", "1", "b", "2", "c", "3", "d", "4")
+	v2 := v1.Insert(setting{"e", "5"}, compareSettings, container.RandomPriority[setting]())
+	v2 = v2.Insert(setting{"b", "20"}, compareSettings, container.RandomPriority[setting]())
+	v2 = v2.Delete(setting{Key: "c"}, compareSettings)
+
+	assert.Equal(t, []container.TreapChange[setting]{
+		{Kind: container.ChangeUpdated, Old: setting{"b", "2"}, New: setting{"b", "20"}},
+		{Kind: container.ChangeRemoved, Old: setting{"c", "3"}},
+		{Kind: container.ChangeAdded, New: setting{"e", "5"}},
+	}, collectChanges(v1, v2, compareSettings, equalSettings))
+
+	// without equal, updates are not reported
+	assert.Len(t, collectChanges(v1, v2, compareSettings, nil), 2)
+	assert.Empty(t, collectChanges(v1, v1, compareSettings, equalSettings))
+	assert.Len(t, collectChanges(nil, v1, compareSettings, equalSettings), 4)
+	assert.Len(t, collectChanges(v1, nil, compareSettings, equalSettings), 4)
+
+	count := 0
+	for range container.TreapChanges(nil, v1, compareSettings, equalSettings) {
+		count++
+		break
+	}
+	assert.Equal(t, 1, count)
+	assert.Equal(t, "updated", container.ChangeUpdated.String())
+}
+
+func TestTreapChangesSkipsSharedSubtrees(t *testing.T) {
+	p := container.IntegerPriority[int]()
+	v1 := container.TreapFromSorted(rangeInts(0, 100000), p)
+	v2 := v1.Insert(123456, intComparer[int], p).Delete(500, intComparer[int])
+
+	compared := 0
+	counting := func(left, right int) int {
+		compared++
+		return intComparer(left, right)
+	}
+	changes := collectChanges(v1, v2, counting, nil)
+	assert.Equal(t, []container.TreapChange[int]{
+		{Kind: container.ChangeRemoved, Old: 500},
+		{Kind: container.ChangeAdded, New: 123456},
+	}, changes)
+	assert.Less(t, compared, 2000)
+}
+
+func TestTreapMerge3(t *testing.T) {
+	base := settings("a", "1", "b", "2", "c", "3", "d", "4")
+	c, p := compareSettings, container.RandomPriority[setting]()
+
+	ours := base.Insert(setting{"a", "ours"}, c, p).
+		Insert(setting{"b", "both"}, c, p).
+		Insert(setting{"c", "ours"}, c, p).
+		Insert(setting{"x", "ours"}, c, p)
+	theirs := base.Insert(setting{"b", "both"}, c, p).
+		Insert(setting{"c", "theirs"}, c, p).
+		Delete(setting{Key: "d"}, c).
+		Insert(setting{"y", "theirs"}, c, p)
+
+	conflicts := []string{}
+	merged := container.TreapMerge3(base, ours, theirs, c, equalSettings, p,
+		func(ours, theirs container.TreapChange[setting]) (setting, bool) {
+			conflicts = append(conflicts, ours.New.Key)
+			return setting{ours.New.Key, ours.New.Value + "+" + theirs.New.Value}, true
+		})
+
+	assert.Equal(t, []string{"c"}, conflicts)
+	assert.Equal(t, []setting{
+		{"a", "ours"},
+		{"b", "both"},
+		{"c", "ours+theirs"},
+		{"x", "ours"},
+		{"y", "theirs"},
+	}, ToSlice(merged))
+	assert.NoError(t, merged.Validate(c))
+
+	// resolving by removing the value
+	merged = container.TreapMerge3(base, ours, theirs, c, equalSettings, p,
+		func(ours, theirs container.TreapChange[setting]) (setting, bool) {
+			return setting{}, false
+		})
+	assert.Nil(t, merged.Find(setting{Key: "c"}, c))
+}
+
+func ToSlice[T any](n *container.Treap[T]) []T {
+	out := []T{}
+	for v := range n.All() {
+		out = append(out, v)
+	}
+	return out
+}
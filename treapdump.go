@@ -0,0 +1,129 @@
+package container
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// WriteASCII writes the treap as an indented tree, one node per line with
+// its value and priority, and its subtree size if sizes is true:
+//
+//	50 p=99
+//	├── L 20 p=80
+//	│   └── R 30 p=7
+//	└── R 70 p=60
+func (n *Treap[T]) WriteASCII(w io.Writer, sizes bool) error {
+	bw := bufio.NewWriter(w)
+	if n == nil {
+		bw.WriteString("(empty)\n")
+	} else {
+		n.writeASCII(bw, "", "", sizes)
+	}
+	return bw.Flush()
+}
+
+// ASCII returns the output of WriteASCII as a string.
+func (n *Treap[T]) ASCII(sizes bool) string {
+	var sb strings.Builder
+	n.WriteASCII(&sb, sizes)
+	return sb.String()
+}
+
+func (n *Treap[T]) writeASCII(w *bufio.Writer, prefix, indent string, sizes bool) {
+	w.WriteString(prefix)
+	w.WriteString(n.label(" ", sizes))
+	w.WriteByte('\n')
+
+	type child struct {
+		name string
+		node *Treap[T]
+	}
+	children := make([]child, 0, 2)
+	if n.Left != nil {
+		children = append(children, child{"L", n.Left})
+	}
+	if n.Right != nil {
+		children = append(children, child{"R", n.Right})
+	}
+	for i, c := range children {
+		if i == len(children)-1 {
+			c.node.writeASCII(w, indent+"└── "+c.name+" ", indent+"    ", sizes)
+		} else {
+			c.node.writeASCII(w, indent+"├── "+c.name+" ", indent+"│   ", sizes)
+		}
+	}
+}
+
+// WriteDot writes the treap as a Graphviz digraph. Every node is labeled
+// with its value and priority, and its subtree size if sizes is true.
+func (n *Treap[T]) WriteDot(w io.Writer, sizes bool) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString("digraph treap {\n\tnode [shape=box];\n")
+	id := 0
+	var walk func(n *Treap[T]) int
+	walk = func(n *Treap[T]) int {
+		self := id
+		id++
+		fmt.Fprintf(bw, "\tn%d [label=%q];\n", self, n.label("\n", sizes))
+		if n.Left != nil {
+			fmt.Fprintf(bw, "\tn%d -> n%d [label=\"L\"];\n", self, walk(n.Left))
+		}
+		if n.Right != nil {
+			fmt.Fprintf(bw, "\tn%d -> n%d [label=\"R\"];\n", self, walk(n.Right))
+		}
+		return self
+	}
+	if n != nil {
+		walk(n)
+	}
+	bw.WriteString("}\n")
+	return bw.Flush()
+}
+
+// Dot returns the output of WriteDot as a string.
+func (n *Treap[T]) Dot(sizes bool) string {
+	var sb strings.Builder
+	n.WriteDot(&sb, sizes)
+	return sb.String()
+}
+
+func (n *Treap[T]) label(sep string, sizes bool) string {
+	label := fmt.Sprintf("%v%sp=%d", n.Value, sep, n.Priority)
+	if sizes {
+		label += fmt.Sprintf("%ssize=%d", sep, n.Len())
+	}
+	return label
+}
+
+// TestingT is the part of testing.TB used by AssertTreap.
+type TestingT interface {
+	Errorf(format string, args ...any)
+}
+
+// AssertTreap checks that the treap is valid (see Validate) and, if any
+// values are given, that it holds exactly those values in order. On failure
+// it reports the error along with a dump of the treap and returns false.
+func AssertTreap[T any](t TestingT, n *Treap[T], c CompareFn[T], want ...T) bool {
+	if h, ok := t.(interface{ Helper() }); ok {
+		h.Helper()
+	}
+	if err := n.Validate(c); err != nil {
+		t.Errorf("invalid treap: %v\n%s", err, n.ASCII(true))
+		return false
+	}
+	if len(want) == 0 {
+		return true
+	}
+	got := make([]T, 0, n.Len())
+	n.ForEach(func(v T) {
+		got = append(got, v)
+	})
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("treap values differ:\n\tgot:  %v\n\twant: %v\n%s", got, want, n.ASCII(true))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,80 @@
+package container_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gabstv/container"
+	"github.com/stretchr/testify/assert"
+)
+
+func dumpSample() *container.Treap[int] {
+	return container.TreapFromSorted([]int{10, 20, 30, 50, 70}, func(v int) int {
+		return map[int]int{10: 3, 20: 80, 30: 7, 50: 99, 70: 60}[v]
+	})
+}
+
+func TestTreapASCII(t *testing.T) {
+	assert.Equal(t, "(empty)\n", (*container.Treap[int])(nil).ASCII(false))
+	assert.Equal(t, strings.Join([]string{
+		"50 p=99",
+		"├── L 20 p=80",
+		"│   ├── L 10 p=3",
+		"│   └── R 30 p=7",
+		"└── R 70 p=60",
+		"",
+	}, "\n"), dumpSample().ASCII(false))
+	assert.Equal(t, strings.Join([]string{
+		"50 p=99 size=5",
+		"├── L 20 p=80 size=3",
+		"│   ├── L 10 p=3 size=1",
+		"│   └── R 30 p=7 size=1",
+		"└── R 70 p=60 size=1",
+		"",
+	}, "\n"), dumpSample().ASCII(true))
+}
+
+func TestTreapDot(t *testing.T) {
+	assert.Equal(t, "digraph treap {\n\tnode [shape=box];\n}\n", (*container.Treap[int])(nil).Dot(false))
+	dot := dumpSample().Dot(true)
+	assert.True(t, strings.HasPrefix(dot, "digraph treap {\n"))
+	assert.Contains(t, dot, "\tn0 [label=\"50\\np=99\\nsize=5\"];\n")
+	assert.Contains(t, dot, "\tn0 -> n1 [label=\"L\"];\n")
+	assert.Contains(t, dot, "\tn1 -> n3 [label=\"R\"];\n")
+	assert.Contains(t, dot, "\tn0 -> n4 [label=\"R\"];\n")
+	assert.Equal(t, 5, strings.Count(dot, "[label=\"L\"]")+strings.Count(dot, "[label=\"R\"]")+1)
+
+	quoted := (&container.Treap[string]{Value: `say "hi"`, Priority: 1}).Dot(false)
+	assert.Contains(t, quoted, `[label="say \"hi\"\np=1"]`)
+}
+
+type recordingT struct {
+	errors []string
+}
+
+func (r *recordingT) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertTreap(t *testing.T) {
+	c := intComparer[int]
+	tree := dumpSample()
+	assert.True(t, container.AssertTreap(t, tree, c))
+	assert.True(t, container.AssertTreap(t, tree, c, 10, 20, 30, 50, 70))
+
+	rec := &recordingT{}
+	assert.False(t, container.AssertTreap(rec, tree, c, 10, 20, 30))
+	if assert.Len(t, rec.errors, 1) {
+		assert.Contains(t, rec.errors[0], "got:  [10 20 30 50 70]")
+		assert.Contains(t, rec.errors[0], "└── R 70 p=60 size=1")
+	}
+
+	rec = &recordingT{}
+	tree.Left.Priority = 100
+	assert.False(t, container.AssertTreap(rec, tree, c))
+	if assert.Len(t, rec.errors, 1) {
+		assert.Contains(t, rec.errors[0], "invalid treap")
+		assert.Contains(t, rec.errors[0], "├── L 20 p=100 size=3")
+	}
+}
@@ -0,0 +1,286 @@
+package container
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var (
+	ErrEmptyTreap       = errors.New("cannot unmarshal an empty treap into a node")
+	ErrCorruptTreapData = errors.New("corrupt treap data")
+)
+
+const treapEncodingVersion = 1
+
+const (
+	treapHasLeft uint8 = 1 << iota
+	treapHasRight
+)
+
+type treapHeader struct {
+	Version    int
+	Count      int
+	Priorities bool
+}
+
+// treapRecord is one node of an encoded treap. With priorities the records
+// are in pre-order and Children tells which children follow, which keeps
+// the exact shape. Without them the records are in order.
+type treapRecord[T any] struct {
+	Value    T     `json:"v"`
+	Priority int   `json:"p"`
+	Children uint8 `json:"c,omitempty"`
+}
+
+// TreapEncoder writes treaps to a stream. Values are encoded with
+// encoding/gob, so T must be encodable by it.
+type TreapEncoder[T any] struct {
+	enc        *gob.Encoder
+	priorities bool
+}
+
+// NewTreapEncoder returns an encoder writing to w. If priorities is true
+// the priorities are kept along with the values, so the decoded treap has
+// the same shape as the encoded one. Otherwise only the values are kept
+// and the decoder picks new priorities.
+func NewTreapEncoder[T any](w io.Writer, priorities bool) *TreapEncoder[T] {
+	return &TreapEncoder[T]{
+		enc:        gob.NewEncoder(w),
+		priorities: priorities,
+	}
+}
+
+// Encode writes the treap to the stream. Several treaps can be written to
+// the same stream by the same encoder.
+func (e *TreapEncoder[T]) Encode(t *Treap[T]) error {
+	if err := e.enc.Encode(treapHeader{treapEncodingVersion, t.Len(), e.priorities}); err != nil {
+		return err
+	}
+	if !e.priorities {
+		for it := t.Iterator(); it.Next(); {
+			if err := e.enc.Encode(treapRecord[T]{Value: it.current.Value}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return t.eachPreOrder(func(r treapRecord[T]) error {
+		return e.enc.Encode(r)
+	})
+}
+
+// TreapDecoder reads treaps written by a TreapEncoder. Nodes are decoded
+// one at a time, so the encoded data never needs to fit in memory at once.
+type TreapDecoder[T any] struct {
+	dec      *gob.Decoder
+	compare  CompareFn[T]
+	priority PriorityFn[T]
+}
+
+// NewTreapDecoder returns a decoder reading from r. c is used to check
+// that the decoded values are in order; it may be nil to skip that check.
+// p picks the priorities of treaps encoded without them, it is random if
+// nil.
+func NewTreapDecoder[T any](r io.Reader, c CompareFn[T], p PriorityFn[T]) *TreapDecoder[T] {
+	if p == nil {
+		p = RandomPriority[T]()
+	}
+	return &TreapDecoder[T]{
+		dec:      gob.NewDecoder(r),
+		compare:  c,
+		priority: p,
+	}
+}
+
+// Decode reads the next treap from the stream. It returns io.EOF when
+// there are no more treaps.
+//
+// The decoded treap is checked as it is built: a wrong node count, values
+// out of order or priorities out of heap order make Decode return an error
+// wrapping ErrCorruptTreapData and, where it applies, a *TreapError.
+func (d *TreapDecoder[T]) Decode() (*Treap[T], error) {
+	var h treapHeader
+	if err := d.dec.Decode(&h); err != nil {
+		return nil, err
+	}
+	if h.Version != treapEncodingVersion || h.Count < 0 {
+		return nil, fmt.Errorf("%w: unsupported header %+v", ErrCorruptTreapData, h)
+	}
+	next := func() (treapRecord[T], error) {
+		var r treapRecord[T]
+		err := d.dec.Decode(&r)
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return r, err
+	}
+
+	if !h.Priorities {
+		var b treapBuilder[T]
+		var prev *T
+		for i := 0; i < h.Count; i++ {
+			r, err := next()
+			if err != nil {
+				return nil, err
+			}
+			if d.compare != nil && prev != nil && d.compare(*prev, r.Value) >= 0 {
+				return nil, corruptTreap(&Treap[T]{Value: r.Value}, ErrTreapOrder)
+			}
+			prev = &r.Value
+			b.push(r.Value, d.priority(r.Value))
+		}
+		return b.finish(), nil
+	}
+	return buildPreOrder(h.Count, d.compare, next)
+}
+
+// eachPreOrder calls fn with the record of every node in pre-order.
+func (n *Treap[T]) eachPreOrder(fn func(r treapRecord[T]) error) error {
+	stack := []*Treap[T]{}
+	if n != nil {
+		stack = append(stack, n)
+	}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		r := treapRecord[T]{Value: n.Value, Priority: n.Priority}
+		if n.Right != nil {
+			r.Children |= treapHasRight
+			stack = append(stack, n.Right)
+		}
+		if n.Left != nil {
+			r.Children |= treapHasLeft
+			stack = append(stack, n.Left)
+		}
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// treapSlot is a child pointer waiting for its node, along with the parent
+// whose priority the node must not exceed and the exclusive bounds of its
+// value, nil when unbounded.
+type treapSlot[T any] struct {
+	child  **Treap[T]
+	parent *Treap[T]
+	lo, hi *T
+}
+
+// buildPreOrder rebuilds a treap from count records in pre-order, checking
+// the heap order and, if c is not nil, the order of the values.
+func buildPreOrder[T any](count int, c CompareFn[T], next func() (treapRecord[T], error)) (*Treap[T], error) {
+	var root *Treap[T]
+	var nodes []*Treap[T]
+	// slots still waiting for their node, the next one to fill on top
+	slots := []treapSlot[T]{{child: &root}}
+	for i := 0; i < count; i++ {
+		r, err := next()
+		if err != nil {
+			return nil, err
+		}
+		if len(slots) == 0 {
+			return nil, fmt.Errorf("%w: too many nodes", ErrCorruptTreapData)
+		}
+		if r.Children&^(treapHasLeft|treapHasRight) != 0 {
+			return nil, fmt.Errorf("%w: bad children %#x", ErrCorruptTreapData, r.Children)
+		}
+		slot := slots[len(slots)-1]
+		slots = slots[:len(slots)-1]
+		n := &Treap[T]{Value: r.Value, Priority: r.Priority}
+		if slot.parent != nil && n.Priority > slot.parent.Priority {
+			return nil, corruptTreap(n, ErrTreapPriority)
+		}
+		if c != nil && ((slot.lo != nil && c(n.Value, *slot.lo) <= 0) || (slot.hi != nil && c(n.Value, *slot.hi) >= 0)) {
+			return nil, corruptTreap(n, ErrTreapOrder)
+		}
+		*slot.child = n
+		if r.Children&treapHasRight != 0 {
+			slots = append(slots, treapSlot[T]{&n.Right, n, &n.Value, slot.hi})
+		}
+		if r.Children&treapHasLeft != 0 {
+			slots = append(slots, treapSlot[T]{&n.Left, n, slot.lo, &n.Value})
+		}
+		nodes = append(nodes, n)
+	}
+	if len(slots) != 0 && count > 0 {
+		return nil, fmt.Errorf("%w: missing nodes", ErrCorruptTreapData)
+	}
+	// in reverse pre-order children come before their parents
+	fixPath(nodes, nil)
+	return root, nil
+}
+
+func corruptTreap[T any](n *Treap[T], err error) error {
+	return fmt.Errorf("%w: %w", ErrCorruptTreapData, &TreapError[T]{n, err})
+}
+
+// MarshalBinary encodes the treap, priorities included, with a
+// TreapEncoder. This also makes treaps encodable with encoding/gob.
+func (n *Treap[T]) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := NewTreapEncoder[T](&buf, true).Encode(n); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary replaces the node with the root of the encoded treap.
+// An empty treap is a nil node, so decoding one returns ErrEmptyTreap; use
+// a TreapDecoder to handle them.
+//
+// Without a comparer only the shape and the heap order of the data are
+// checked, see Validate to check the order of the values too.
+func (n *Treap[T]) UnmarshalBinary(data []byte) error {
+	root, err := NewTreapDecoder[T](bytes.NewReader(data), nil, nil).Decode()
+	if err != nil {
+		return err
+	}
+	return n.replace(root)
+}
+
+// MarshalJSON encodes the treap as an array with the values, priorities
+// and children of the nodes in pre-order, which keeps the exact shape. An
+// empty treap is encoded as null.
+func (n *Treap[T]) MarshalJSON() ([]byte, error) {
+	if n == nil {
+		return []byte("null"), nil
+	}
+	records := make([]treapRecord[T], 0, n.Len())
+	n.eachPreOrder(func(r treapRecord[T]) error {
+		records = append(records, r)
+		return nil
+	})
+	return json.Marshal(records)
+}
+
+// UnmarshalJSON replaces the node with the root of the encoded treap. As
+// with UnmarshalBinary, the order of the values is not checked.
+func (n *Treap[T]) UnmarshalJSON(data []byte) error {
+	var records []treapRecord[T]
+	if err := json.Unmarshal(data, &records); err != nil {
+		return err
+	}
+	i := 0
+	root, err := buildPreOrder(len(records), nil, func() (treapRecord[T], error) {
+		i++
+		return records[i-1], nil
+	})
+	if err != nil {
+		return err
+	}
+	return n.replace(root)
+}
+
+func (n *Treap[T]) replace(root *Treap[T]) error {
+	if root == nil {
+		return ErrEmptyTreap
+	}
+	*n = *root
+	return nil
+}
@@ -0,0 +1,177 @@
+package container_test
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/gabstv/container"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTreapBinaryRoundTrip(t *testing.T) {
+	rand.Seed(42)
+
+	x := ToTreap(rand.Perm(2000))
+	data, err := x.MarshalBinary()
+	assert.NoError(t, err)
+
+	y := new(container.Treap[int])
+	assert.NoError(t, y.UnmarshalBinary(data))
+	assert.True(t, sameShape(x, y))
+	assert.NoError(t, y.Validate(intComparer[int]))
+
+	var empty *container.Treap[int]
+	data, err = empty.MarshalBinary()
+	assert.NoError(t, err)
+	assert.ErrorIs(t, new(container.Treap[int]).UnmarshalBinary(data), container.ErrEmptyTreap)
+	assert.Error(t, new(container.Treap[int]).UnmarshalBinary([]byte("garbage")))
+}
+
+func TestTreapGob(t *testing.T) {
+	type snapshot struct {
+		Name  string
+		Items *container.Treap[string]
+		Empty *container.Treap[string]
+	}
+
+	items := container.TreapFromSlice([]string{"b", "c", "a"}, compareStrings, container.StringPriority())
+	var buf bytes.Buffer
+	assert.NoError(t, gob.NewEncoder(&buf).Encode(snapshot{Name: "v1", Items: items}))
+
+	var out snapshot
+	assert.NoError(t, gob.NewDecoder(&buf).Decode(&out))
+	assert.Equal(t, "v1", out.Name)
+	assert.True(t, sameShape(items, out.Items))
+	assert.Nil(t, out.Empty)
+}
+
+func TestTreapJSON(t *testing.T) {
+	x := container.TreapFromSorted([]int{1, 2, 3}, func(v int) int {
+		return []int{5, 9, 1}[v-1]
+	})
+	data, err := json.Marshal(x)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"v":2,"p":9,"c":3},{"v":1,"p":5},{"v":3,"p":1}]`, string(data))
+
+	var y *container.Treap[int]
+	assert.NoError(t, json.Unmarshal(data, &y))
+	assert.True(t, sameShape(x, y))
+	assert.Equal(t, 3, y.Len())
+
+	var empty *container.Treap[int]
+	data, err = json.Marshal(empty)
+	assert.NoError(t, err)
+	assert.Equal(t, "null", string(data))
+	assert.NoError(t, json.Unmarshal(data, &y))
+	assert.Nil(t, y)
+
+	// a node claiming a child that is missing
+	assert.ErrorIs(t, json.Unmarshal([]byte(`[{"v":1,"p":1,"c":1}]`), &y), container.ErrCorruptTreapData)
+	// unknown children flags
+	assert.ErrorIs(t, json.Unmarshal([]byte(`[{"v":1,"p":1,"c":4}]`), &y), container.ErrCorruptTreapData)
+	// a child with a higher priority than its parent
+	err = json.Unmarshal([]byte(`[{"v":2,"p":1,"c":1},{"v":1,"p":5}]`), &y)
+	assert.ErrorIs(t, err, container.ErrCorruptTreapData)
+	assert.ErrorIs(t, err, container.ErrTreapPriority)
+}
+
+func TestTreapDecoderCorrupt(t *testing.T) {
+	encode := func(priorities bool, records ...any) *bytes.Buffer {
+		var buf bytes.Buffer
+		enc := gob.NewEncoder(&buf)
+		assert.NoError(t, enc.Encode(struct {
+			Version    int
+			Count      int
+			Priorities bool
+		}{1, len(records), priorities}))
+		for _, r := range records {
+			assert.NoError(t, enc.Encode(r))
+		}
+		return &buf
+	}
+	type record struct {
+		Value    int
+		Priority int
+		Children uint8
+	}
+	decode := func(buf *bytes.Buffer) error {
+		_, err := container.NewTreapDecoder(buf, intComparer[int], nil).Decode()
+		return err
+	}
+
+	// 3 on the left of 2
+	err := decode(encode(true, record{2, 9, 1}, record{3, 5, 0}))
+	assert.ErrorIs(t, err, container.ErrCorruptTreapData)
+	assert.ErrorIs(t, err, container.ErrTreapOrder)
+	// 5 on the right of 2, but in the left subtree of 4
+	err = decode(encode(true, record{4, 9, 1}, record{2, 8, 2}, record{5, 7, 0}))
+	assert.ErrorIs(t, err, container.ErrTreapOrder)
+	err = decode(encode(true, record{2, 1, 2}, record{3, 5, 0}))
+	assert.ErrorIs(t, err, container.ErrTreapPriority)
+	// a node claiming two children with only one following
+	err = decode(encode(true, record{2, 9, 3}, record{1, 5, 0}))
+	assert.ErrorIs(t, err, container.ErrCorruptTreapData)
+	// values without priorities must come in order
+	err = decode(encode(false, record{Value: 1}, record{Value: 3}, record{Value: 2}))
+	assert.ErrorIs(t, err, container.ErrTreapOrder)
+	err = decode(encode(false, record{Value: 1}, record{Value: 1}))
+	assert.ErrorIs(t, err, container.ErrTreapOrder)
+
+	// a nil comparer skips the order checks only
+	_, err = container.NewTreapDecoder[int](encode(true, record{2, 9, 1}, record{3, 5, 0}), nil, nil).Decode()
+	assert.NoError(t, err)
+	assert.NoError(t, decode(encode(true, record{2, 9, 3}, record{1, 5, 0}, record{3, 5, 0})))
+}
+
+func TestTreapEncoderStream(t *testing.T) {
+	rand.Seed(42)
+
+	x := ToTreap(rand.Perm(500))
+	y := ToTreap(rand.Perm(10))
+	var buf bytes.Buffer
+	shaped := container.NewTreapEncoder[int](&buf, true)
+	assert.NoError(t, shaped.Encode(x))
+	assert.NoError(t, shaped.Encode(nil))
+	assert.NoError(t, shaped.Encode(y))
+
+	dec := container.NewTreapDecoder(&buf, intComparer[int], nil)
+	got, err := dec.Decode()
+	assert.NoError(t, err)
+	assert.True(t, sameShape(x, got))
+	got, err = dec.Decode()
+	assert.NoError(t, err)
+	assert.Nil(t, got)
+	got, err = dec.Decode()
+	assert.NoError(t, err)
+	assert.True(t, sameShape(y, got))
+	_, err = dec.Decode()
+	assert.Equal(t, io.EOF, err)
+
+	// without priorities the decoder picks new ones
+	buf.Reset()
+	assert.NoError(t, container.NewTreapEncoder[int](&buf, false).Encode(y))
+	got, err = container.NewTreapDecoder(&buf, intComparer[int], container.IntegerPriority[int]()).Decode()
+	assert.NoError(t, err)
+	assert.Equal(t, ToArray(y), ToArray(got))
+	assert.True(t, sameShape(container.TreapFromSorted(ToArray(y), container.IntegerPriority[int]()), got))
+
+	buf.Reset()
+	assert.NoError(t, container.NewTreapEncoder[int](&buf, true).Encode(x))
+	truncated := bytes.NewReader(buf.Bytes()[:buf.Len()/2])
+	_, err = container.NewTreapDecoder[int](truncated, nil, nil).Decode()
+	assert.Error(t, err)
+}
+
+func compareStrings(left, right string) int {
+	switch {
+	case left < right:
+		return -1
+	case left > right:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,179 @@
+package container
+
+import "iter"
+
+// TreapIterator walks a treap in order using an explicit stack, so it
+// doesn't recurse no matter how deep the treap is. Since treaps are
+// immutable, the iterator keeps working on the version it was created from
+// even if newer versions are derived while it is in use.
+//
+// A new iterator is positioned before the first value; call Next to advance:
+//
+//	for it := t.Iterator(); it.Next(); {
+//		fmt.Println(it.Value())
+//	}
+type TreapIterator[T any] struct {
+	root    *Treap[T]
+	stack   []*Treap[T]
+	current *Treap[T]
+	reverse bool
+}
+
+// Iterator returns an iterator over the values in ascending order.
+func (n *Treap[T]) Iterator() *TreapIterator[T] {
+	it := &TreapIterator[T]{root: n}
+	it.Reset()
+	return it
+}
+
+// ReverseIterator returns an iterator over the values in descending order.
+func (n *Treap[T]) ReverseIterator() *TreapIterator[T] {
+	it := &TreapIterator[T]{root: n, reverse: true}
+	it.Reset()
+	return it
+}
+
+// Reset moves the iterator back before the first value.
+func (it *TreapIterator[T]) Reset() {
+	it.stack = it.stack[:0]
+	it.current = nil
+	it.pushSpine(it.root)
+}
+
+// Seek positions the iterator so that the next call to Next moves to the
+// first value that compares greater-than or equal to v (less-than or equal
+// to v for reverse iterators).
+func (it *TreapIterator[T]) Seek(v T, c CompareFn[T]) {
+	it.stack = it.stack[:0]
+	it.current = nil
+	n := it.root
+	for n != nil {
+		diff := c(n.Value, v)
+		if it.reverse {
+			diff = -diff
+		}
+		switch {
+		case diff == 0:
+			it.stack = append(it.stack, n)
+			return
+		case diff > 0:
+			it.stack = append(it.stack, n)
+			n = it.before(n)
+		default:
+			n = it.after(n)
+		}
+	}
+}
+
+// Next advances the iterator and reports whether there is a value at the
+// new position.
+func (it *TreapIterator[T]) Next() bool {
+	if len(it.stack) == 0 {
+		it.current = nil
+		return false
+	}
+	it.current = it.stack[len(it.stack)-1]
+	it.stack = it.stack[:len(it.stack)-1]
+	it.pushSpine(it.after(it.current))
+	return true
+}
+
+// Value returns the value at the current position. It returns the zero
+// value if Next hasn't been called or returned false.
+func (it *TreapIterator[T]) Value() T {
+	if it.current == nil {
+		var zv T
+		return zv
+	}
+	return it.current.Value
+}
+
+// Node returns the node at the current position, or nil if Next hasn't been
+// called or returned false.
+func (it *TreapIterator[T]) Node() *Treap[T] {
+	return it.current
+}
+
+// Values returns an iterator over the remaining values.
+func (it *TreapIterator[T]) Values() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for it.Next() {
+			if !yield(it.current.Value) {
+				return
+			}
+		}
+	}
+}
+
+// pushSpine pushes n and the chain of nodes that come before it.
+func (it *TreapIterator[T]) pushSpine(n *Treap[T]) {
+	for n != nil {
+		it.stack = append(it.stack, n)
+		n = it.before(n)
+	}
+}
+
+// before returns the subtree visited ahead of n in the iteration order.
+func (it *TreapIterator[T]) before(n *Treap[T]) *Treap[T] {
+	if it.reverse {
+		return n.Right
+	}
+	return n.Left
+}
+
+// after returns the subtree visited after n in the iteration order.
+func (it *TreapIterator[T]) after(n *Treap[T]) *Treap[T] {
+	if it.reverse {
+		return n.Left
+	}
+	return n.Right
+}
+
+// All returns an iterator over the values in ascending order. Since set
+// operations never modify their operands, the iteration sees the treap as
+// it was when it started, whatever is derived from it in the meantime.
+func (n *Treap[T]) All() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for it := n.Iterator(); it.Next(); {
+			if !yield(it.current.Value) {
+				return
+			}
+		}
+	}
+}
+
+// Backward returns an iterator over the values in descending order. Like
+// All, it sees the treap as it was when it started.
+func (n *Treap[T]) Backward() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for it := n.ReverseIterator(); it.Next(); {
+			if !yield(it.current.Value) {
+				return
+			}
+		}
+	}
+}
+
+// Keys is the same as All; a treap's values are its keys.
+func (n *Treap[T]) Keys() iter.Seq[T] {
+	return n.All()
+}
+
+// Values is the same as All.
+func (n *Treap[T]) Values() iter.Seq[T] {
+	return n.All()
+}
+
+// From returns an iterator over the values that compare greater-than or
+// equal to v, in ascending order.
+func (n *Treap[T]) From(v T, c CompareFn[T]) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		it := n.Iterator()
+		it.Seek(v, c)
+		for it.Next() {
+			if !yield(it.current.Value) {
+				return
+			}
+		}
+	}
+}
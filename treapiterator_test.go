@@ -0,0 +1,100 @@
+package container_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/gabstv/container"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTreapIterator(t *testing.T) {
+	x := ToTreap([]int{30, 10, 50, 20, 40})
+
+	var values []int
+	for it := x.Iterator(); it.Next(); {
+		values = append(values, it.Value())
+	}
+	assert.Equal(t, []int{10, 20, 30, 40, 50}, values)
+
+	values = nil
+	for it := x.ReverseIterator(); it.Next(); {
+		values = append(values, it.Value())
+	}
+	assert.Equal(t, []int{50, 40, 30, 20, 10}, values)
+
+	it := x.Iterator()
+	it.Seek(25, intComparer[int])
+	assert.True(t, it.Next())
+	assert.Equal(t, 30, it.Value())
+	assert.Equal(t, 30, it.Node().Value)
+	it.Seek(40, intComparer[int])
+	assert.True(t, it.Next())
+	assert.Equal(t, 40, it.Value())
+	it.Seek(60, intComparer[int])
+	assert.False(t, it.Next())
+	assert.Nil(t, it.Node())
+	assert.Equal(t, 0, it.Value())
+	it.Reset()
+	assert.True(t, it.Next())
+	assert.Equal(t, 10, it.Value())
+
+	rit := x.ReverseIterator()
+	rit.Seek(25, intComparer[int])
+	values = nil
+	for rit.Next() {
+		values = append(values, rit.Value())
+	}
+	assert.Equal(t, []int{20, 10}, values)
+
+	var empty *container.Treap[int]
+	assert.False(t, empty.Iterator().Next())
+}
+
+func TestTreapSeq(t *testing.T) {
+	x := ToTreap([]int{30, 10, 50, 20, 40})
+
+	var values []int
+	for v := range x.All() {
+		values = append(values, v)
+	}
+	assert.Equal(t, []int{10, 20, 30, 40, 50}, values)
+	assert.Equal(t, values, slices.Collect(x.Keys()))
+	assert.Equal(t, values, slices.Collect(x.Values()))
+
+	values = nil
+	for v := range x.Backward() {
+		if v < 30 {
+			break
+		}
+		values = append(values, v)
+	}
+	assert.Equal(t, []int{50, 40, 30}, values)
+
+	values = nil
+	for v := range x.From(20, intComparer[int]) {
+		values = append(values, v)
+	}
+	assert.Equal(t, []int{20, 30, 40, 50}, values)
+
+	it := x.Iterator()
+	it.Next()
+	values = nil
+	for v := range it.Values() {
+		values = append(values, v)
+	}
+	assert.Equal(t, []int{20, 30, 40, 50}, values)
+}
+
+func TestTreapIteratorDeepTree(t *testing.T) {
+	// a degenerate treap is as deep as it is long
+	var x *container.Treap[int]
+	for kk := 0; kk < 100000; kk++ {
+		x = &container.Treap[int]{Value: kk, Priority: kk, Left: x}
+	}
+	count := 0
+	x.ForEach(func(int) {
+		count++
+	})
+	assert.Equal(t, 100000, count)
+}
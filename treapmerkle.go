@@ -0,0 +1,154 @@
+package container
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"sync"
+)
+
+// MerkleHasher computes Merkle digests of treaps: the digest of a node
+// covers its value, its priority and the digests of its children, so two
+// subtrees with the same digest hold the same values in the same shape.
+//
+// Digests are computed lazily and memoized per node. Since treaps share
+// nodes between versions, hashing a new version only hashes the nodes that
+// were copied to derive it. The memo holds the most recently used digests,
+// about as many as the size given to NewMerkleHasherSize; older ones are
+// forgotten, releasing their nodes, and computed again if needed.
+//
+// Nodes must not be modified after they were hashed. Replicas should build
+// their treaps with a deterministic PriorityFn (see HashPriority) so that
+// equal contents lead to equal shapes, and so to equal digests.
+type MerkleHasher[T any] struct {
+	hash func(v T) []byte
+	lock sync.Mutex
+	// the memo is two generations of up to half digests each: when recent
+	// is full, it replaces old, and digests found in old move to recent
+	recent, old map[*Treap[T]][sha256.Size]byte
+	half        int
+}
+
+// DefaultMerkleCacheSize is the default number of digests memoized by a
+// MerkleHasher.
+const DefaultMerkleCacheSize = 1 << 16
+
+// TreapRange is an inclusive range of values.
+type TreapRange[T any] struct {
+	Lo, Hi T
+}
+
+// NewMerkleHasher returns a hasher using hash to turn values into bytes.
+// hash must be deterministic; it doesn't need to be a cryptographic hash,
+// any encoding of the value works. It memoizes DefaultMerkleCacheSize
+// digests.
+func NewMerkleHasher[T any](hash func(v T) []byte) *MerkleHasher[T] {
+	return NewMerkleHasherSize(hash, DefaultMerkleCacheSize)
+}
+
+// NewMerkleHasherSize is like NewMerkleHasher, memoizing about size
+// digests.
+func NewMerkleHasherSize[T any](hash func(v T) []byte, size int) *MerkleHasher[T] {
+	h := &MerkleHasher[T]{
+		hash: hash,
+		half: max(size/2, 1),
+	}
+	h.Reset()
+	return h
+}
+
+// Digest returns the digest of the treap. The digest of an empty treap is
+// all zeroes.
+func (h *MerkleHasher[T]) Digest(n *Treap[T]) [sha256.Size]byte {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return h.digest(n)
+}
+
+// Reset forgets all the memoized digests, releasing the nodes they were
+// computed for.
+func (h *MerkleHasher[T]) Reset() {
+	h.lock.Lock()
+	h.recent = make(map[*Treap[T]][sha256.Size]byte)
+	h.old = nil
+	h.lock.Unlock()
+}
+
+func (h *MerkleHasher[T]) memoize(n *Treap[T], sum [sha256.Size]byte) {
+	if len(h.recent) >= h.half {
+		h.old, h.recent = h.recent, make(map[*Treap[T]][sha256.Size]byte, h.half)
+	}
+	h.recent[n] = sum
+}
+
+func (h *MerkleHasher[T]) digest(n *Treap[T]) [sha256.Size]byte {
+	if n == nil {
+		return [sha256.Size]byte{}
+	}
+	if sum, ok := h.recent[n]; ok {
+		return sum
+	}
+	if sum, ok := h.old[n]; ok {
+		delete(h.old, n)
+		h.memoize(n, sum)
+		return sum
+	}
+
+	left, right := h.digest(n.Left), h.digest(n.Right)
+	value := h.hash(n.Value)
+	d := sha256.New()
+	var scratch [binary.MaxVarintLen64]byte
+	d.Write(scratch[:binary.PutVarint(scratch[:], int64(n.Priority))])
+	d.Write(scratch[:binary.PutUvarint(scratch[:], uint64(len(value)))])
+	d.Write(value)
+	d.Write(left[:])
+	d.Write(right[:])
+	var sum [sha256.Size]byte
+	d.Sum(sum[:0])
+	h.memoize(n, sum)
+	return sum
+}
+
+// DiffRanges compares two treaps top-down and returns, in ascending order,
+// the ranges of values where they may differ. Subtrees with equal digests
+// are skipped. Where the shapes diverge, the range spans both subtrees, so
+// it can be larger than the actual differences; syncing the values of the
+// returned ranges is enough to make the treaps equal.
+func (h *MerkleHasher[T]) DiffRanges(a, b *Treap[T], c CompareFn[T]) []TreapRange[T] {
+	var ranges []TreapRange[T]
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.diffRanges(a, b, c, &ranges)
+	return ranges
+}
+
+func (h *MerkleHasher[T]) diffRanges(a, b *Treap[T], c CompareFn[T], ranges *[]TreapRange[T]) {
+	if h.digest(a) == h.digest(b) {
+		return
+	}
+	if a != nil && b != nil && a.Priority == b.Priority && c(a.Value, b.Value) == 0 {
+		h.diffRanges(a.Left, b.Left, c, ranges)
+		if string(h.hash(a.Value)) != string(h.hash(b.Value)) {
+			*ranges = append(*ranges, TreapRange[T]{a.Value, a.Value})
+		}
+		h.diffRanges(a.Right, b.Right, c, ranges)
+		return
+	}
+
+	// the shapes diverge here, cover both subtrees
+	var r TreapRange[T]
+	switch {
+	case a == nil:
+		r = TreapRange[T]{b.Min().Value, b.Max().Value}
+	case b == nil:
+		r = TreapRange[T]{a.Min().Value, a.Max().Value}
+	default:
+		r = TreapRange[T]{a.Min().Value, a.Max().Value}
+		if lo := b.Min().Value; c(lo, r.Lo) < 0 {
+			r.Lo = lo
+		}
+		if hi := b.Max().Value; c(hi, r.Hi) > 0 {
+			r.Hi = hi
+		}
+	}
+	*ranges = append(*ranges, r)
+}
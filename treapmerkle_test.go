@@ -0,0 +1,123 @@
+package container_test
+
+import (
+	"encoding/binary"
+	"math/rand"
+	"testing"
+
+	"github.com/gabstv/container"
+	"github.com/stretchr/testify/assert"
+)
+
+func hashInt(v int) []byte {
+	return binary.AppendVarint(nil, int64(v))
+}
+
+func TestMerkleDigest(t *testing.T) {
+	c, p := intComparer[int], container.IntegerPriority[int]()
+	h := container.NewMerkleHasher(hashInt)
+
+	input := rand.Perm(5000)
+	x := container.TreapFromSlice(input, c, p)
+	var y *container.Treap[int]
+	for _, v := range input {
+		y = y.Insert(v, c, p)
+	}
+	assert.Equal(t, h.Digest(x), h.Digest(y))
+	assert.NotEqual(t, h.Digest(x), h.Digest(x.Delete(10, c)))
+	assert.Equal(t, [32]byte{}, h.Digest(nil))
+
+	// only the nodes copied by the edit are hashed again
+	calls := 0
+	counting := container.NewMerkleHasher(func(v int) []byte {
+		calls++
+		return hashInt(v)
+	})
+	counting.Digest(x)
+	assert.Equal(t, 5000, calls)
+	calls = 0
+	counting.Digest(x.Insert(7777, c, p))
+	assert.Less(t, calls, 100)
+
+	// Reset drops the memo
+	digest := counting.Digest(x)
+	counting.Reset()
+	calls = 0
+	assert.Equal(t, digest, counting.Digest(x))
+	assert.Equal(t, 5000, calls)
+	// a small memo forgets the older digests
+	small := container.NewMerkleHasherSize(func(v int) []byte {
+		calls++
+		return hashInt(v)
+	}, 100)
+	calls = 0
+	assert.Equal(t, digest, small.Digest(x))
+	assert.Equal(t, 5000, calls)
+	calls = 0
+	small.Digest(x)
+	assert.Zero(t, calls)
+	other := make([]int, 5000)
+	for kk := range other {
+		other[kk] = -kk - 1
+	}
+	small.Digest(container.TreapFromSlice(other, c, p))
+	calls = 0
+	assert.Equal(t, digest, small.Digest(x))
+	assert.Equal(t, 5000, calls)
+}
+
+func TestMerkleDiffRanges(t *testing.T) {
+	c, p := intComparer[int], container.IntegerPriority[int]()
+	h := container.NewMerkleHasher(hashInt)
+
+	x := container.TreapFromSorted(rangeInts(0, 10000), p)
+	assert.Empty(t, h.DiffRanges(x, x, c))
+
+	y := x.Delete(1234, c).Insert(20000, c, p).Delete(5000, c)
+	ranges := h.DiffRanges(x, y, c)
+	assert.NotEmpty(t, ranges)
+	for i, r := range ranges {
+		assert.LessOrEqual(t, r.Lo, r.Hi)
+		if i > 0 {
+			assert.Less(t, ranges[i-1].Hi, r.Lo)
+		}
+	}
+	covered := func(v int) bool {
+		for _, r := range ranges {
+			if r.Lo <= v && v <= r.Hi {
+				return true
+			}
+		}
+		return false
+	}
+	assert.True(t, covered(1234))
+	assert.True(t, covered(5000))
+	assert.True(t, covered(20000))
+	assert.False(t, covered(7000))
+
+	// syncing the ranges makes both treaps equal
+	synced := x
+	for _, r := range ranges {
+		left, _, _ := synced.Split(r.Lo, c)
+		_, _, right := synced.Split(r.Hi, c)
+		var middle *container.Treap[int]
+		y.Range(r.Lo, r.Hi, container.RangeClosed, c, func(v int) bool {
+			middle = middle.Insert(v, c, p)
+			return true
+		})
+		synced = left.Union(middle, c, true).Union(right, c, true)
+	}
+	assert.Equal(t, h.Digest(y), h.Digest(synced))
+	assert.Equal(t, ToArray(y), ToArray(synced))
+
+	// updated values with the same position are reported on their own
+	type kv struct{ K, V int }
+	kvc := func(a, b kv) int { return a.K - b.K }
+	kvp := container.HashPriority(func(v kv) uint64 { return uint64(v.K) })
+	kh := container.NewMerkleHasher(func(v kv) []byte {
+		return binary.AppendVarint(hashInt(v.K), int64(v.V))
+	})
+	a := container.TreapFromSorted([]kv{{1, 1}, {2, 2}, {3, 3}}, kvp)
+	b := a.Insert(kv{2, 20}, kvc, kvp)
+	assert.Equal(t, []container.TreapRange[kv]{{kv{2, 2}, kv{2, 2}}}, kh.DiffRanges(a, b, kvc))
+}
@@ -0,0 +1,157 @@
+package container
+
+import (
+	"math/bits"
+	"runtime"
+	"sync"
+)
+
+// DefaultParallelThreshold is the combined size under which the parallel
+// set operations fall back to their sequential versions when they are given
+// a threshold <= 0. Below it, the cost of spawning goroutines outweighs the
+// work saved.
+const DefaultParallelThreshold = 8192
+
+// ParallelUnion is the same as Union, but recurses into the left and right
+// subtrees concurrently as long as the two treaps hold at least threshold
+// values combined. The result is identical to the one of Union.
+//
+// Only the top levels of the recursion are forked, enough to keep
+// GOMAXPROCS goroutines busy (see forkDepth); deeper levels run sequentially
+// whatever the threshold.
+func (n *Treap[T]) ParallelUnion(other *Treap[T], c CompareFn[T], overwrite bool, threshold int) *Treap[T] {
+	if threshold <= 0 {
+		threshold = DefaultParallelThreshold
+	}
+	return n.parallelUnion(other, c, overwrite, threshold, forkDepth())
+}
+
+func (n *Treap[T]) parallelUnion(other *Treap[T], c CompareFn[T], overwrite bool, threshold, depth int) *Treap[T] {
+	if n == nil {
+		return other
+	}
+	if other == nil {
+		return n
+	}
+	if depth <= 0 || n.Len()+other.Len() < threshold {
+		return n.Union(other, c, overwrite)
+	}
+
+	if n.Priority < other.Priority {
+		other, n, overwrite = n, other, !overwrite
+	}
+
+	left, dupe, right := other.Split(n.Value, c)
+	value := n.Value
+	if overwrite && dupe != nil {
+		value = dupe.Value
+	}
+	fork(func() {
+		left = n.Left.parallelUnion(left, c, overwrite, threshold, depth-1)
+	}, func() {
+		right = n.Right.parallelUnion(right, c, overwrite, threshold, depth-1)
+	})
+	return newTreap(value, n.Priority, left, right, nil)
+}
+
+// ParallelIntersection is the same as Intersection, but recurses into the
+// left and right subtrees concurrently as long as the two treaps hold at
+// least threshold values combined.
+func (n *Treap[T]) ParallelIntersection(other *Treap[T], c CompareFn[T], threshold int) *Treap[T] {
+	if threshold <= 0 {
+		threshold = DefaultParallelThreshold
+	}
+	return n.parallelIntersection(other, c, threshold, forkDepth())
+}
+
+func (n *Treap[T]) parallelIntersection(other *Treap[T], c CompareFn[T], threshold, depth int) *Treap[T] {
+	if n == nil || other == nil {
+		return nil
+	}
+	if depth <= 0 || n.Len()+other.Len() < threshold {
+		return n.Intersection(other, c)
+	}
+
+	if n.Priority < other.Priority {
+		n, other = other, n
+	}
+
+	left, found, right := other.Split(n.Value, c)
+	fork(func() {
+		left = n.Left.parallelIntersection(left, c, threshold, depth-1)
+	}, func() {
+		right = n.Right.parallelIntersection(right, c, threshold, depth-1)
+	})
+
+	if found == nil {
+		return left.join(right, nil)
+	}
+	return newTreap(n.Value, n.Priority, left, right, nil)
+}
+
+// ParallelDiff is the same as Diff, but recurses into the left and right
+// subtrees concurrently as long as the two treaps hold at least threshold
+// values combined.
+func (n *Treap[T]) ParallelDiff(other *Treap[T], c CompareFn[T], threshold int) *Treap[T] {
+	if threshold <= 0 {
+		threshold = DefaultParallelThreshold
+	}
+	return n.parallelDiff(other, c, threshold, forkDepth())
+}
+
+func (n *Treap[T]) parallelDiff(other *Treap[T], c CompareFn[T], threshold, depth int) *Treap[T] {
+	if n == nil || other == nil {
+		return n
+	}
+	if depth <= 0 || n.Len()+other.Len() < threshold {
+		return n.Diff(other, c)
+	}
+
+	var result *Treap[T]
+	if n.Priority >= other.Priority {
+		left, dupe, right := other.Split(n.Value, c)
+		fork(func() {
+			left = n.Left.parallelDiff(left, c, threshold, depth-1)
+		}, func() {
+			right = n.Right.parallelDiff(right, c, threshold, depth-1)
+		})
+		if dupe != nil {
+			return left.join(right, nil)
+		}
+		result = newTreap(n.Value, n.Priority, left, right, nil)
+	} else {
+		left, _, right := n.Split(other.Value, c)
+		fork(func() {
+			left = left.parallelDiff(other.Left, c, threshold, depth-1)
+		}, func() {
+			right = right.parallelDiff(other.Right, c, threshold, depth-1)
+		})
+		result = left.join(right, nil)
+	}
+
+	// see Diff
+	if result.Len() == n.Len() {
+		return n
+	}
+	return result
+}
+
+// forkDepth returns how many levels of the recursion the parallel set
+// operations fork: one more than log2(GOMAXPROCS), so that there are about
+// twice as many goroutines as processors to even out unbalanced splits.
+func forkDepth() int {
+	return bits.Len(uint(runtime.GOMAXPROCS(0)))
+}
+
+// fork runs a in a new goroutine and b in the current one, and waits for
+// both to finish.
+func fork(a, b func()) {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		a()
+	}()
+	b()
+	wg.Wait()
+}
@@ -0,0 +1,132 @@
+package container_test
+
+import (
+	"math/rand"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/gabstv/container"
+)
+
+func parallelInputs(size int) (set1, set2 *container.Treap[int]) {
+	rand.Seed(42)
+
+	input1 := []int{}
+	input2 := []int{}
+	start := 0
+	for kk := 0; kk < size; kk++ {
+		start += 1 + rand.Intn(10)
+		input1 = append(input1, start)
+		start += 1 + rand.Intn(10)
+		input2 = append(input2, start)
+		if kk%2 == 0 {
+			input1 = append(input1, start)
+		}
+	}
+	return container.TreapFromSorted(input1, nil), container.TreapFromSorted(input2, nil)
+}
+
+func TestParallelSetOperations(t *testing.T) {
+	// fork a few levels even on a single processor
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(8))
+
+	set1, set2 := parallelInputs(20000)
+	c := intComparer[int]
+
+	for _, threshold := range []int{0, 1, 100} {
+		union := set1.ParallelUnion(set2, c, false, threshold)
+		if !reflect.DeepEqual(union, set1.Union(set2, c, false)) {
+			t.Fatal("ParallelUnion diverged", threshold)
+		}
+		intersection := set1.ParallelIntersection(set2, c, threshold)
+		if !reflect.DeepEqual(intersection, set1.Intersection(set2, c)) {
+			t.Fatal("ParallelIntersection diverged", threshold)
+		}
+		diff := set1.ParallelDiff(set2, c, threshold)
+		if !reflect.DeepEqual(diff, set1.Diff(set2, c)) {
+			t.Fatal("ParallelDiff diverged", threshold)
+		}
+	}
+}
+
+func TestParallelUnionOverwrite(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(8))
+
+	type tagged struct{ key, tag int }
+	c := func(left, right tagged) int { return left.key - right.key }
+	p := container.HashPriority(func(v tagged) uint64 { return uint64(v.key) })
+
+	var old, updates []tagged
+	for kk := 0; kk < 5000; kk++ {
+		old = append(old, tagged{kk * 2, 1})
+		updates = append(updates, tagged{kk * 3, 2})
+	}
+	set1 := container.TreapFromSorted(old, p)
+	set2 := container.TreapFromSorted(updates, p)
+
+	for _, overwrite := range []bool{false, true} {
+		for _, threshold := range []int{1, 100} {
+			union := set1.ParallelUnion(set2, c, overwrite, threshold)
+			if !sameShape(union, set1.Union(set2, c, overwrite)) {
+				t.Fatal("ParallelUnion diverged", overwrite, threshold)
+			}
+			// keys in both treaps come from set2 only when overwriting
+			want := 1
+			if overwrite {
+				want = 2
+			}
+			if v := union.Find(tagged{key: 6}, c).Value; v.tag != want {
+				t.Fatal("ParallelUnion kept the wrong duplicate", overwrite, v)
+			}
+		}
+	}
+}
+
+func BenchmarkParallelUnion(b *testing.B) {
+	set1, set2 := parallelInputs(200000)
+	b.ResetTimer()
+	for kk := 0; kk < b.N; kk++ {
+		set1.ParallelUnion(set2, intComparer[int], false, 0)
+	}
+}
+
+func BenchmarkSequentialUnion(b *testing.B) {
+	set1, set2 := parallelInputs(200000)
+	b.ResetTimer()
+	for kk := 0; kk < b.N; kk++ {
+		set1.Union(set2, intComparer[int], false)
+	}
+}
+
+func BenchmarkParallelIntersection(b *testing.B) {
+	set1, set2 := parallelInputs(200000)
+	b.ResetTimer()
+	for kk := 0; kk < b.N; kk++ {
+		set1.ParallelIntersection(set2, intComparer[int], 0)
+	}
+}
+
+func BenchmarkSequentialIntersection(b *testing.B) {
+	set1, set2 := parallelInputs(200000)
+	b.ResetTimer()
+	for kk := 0; kk < b.N; kk++ {
+		set1.Intersection(set2, intComparer[int])
+	}
+}
+
+func BenchmarkParallelDiff(b *testing.B) {
+	set1, set2 := parallelInputs(200000)
+	b.ResetTimer()
+	for kk := 0; kk < b.N; kk++ {
+		set1.ParallelDiff(set2, intComparer[int], 0)
+	}
+}
+
+func BenchmarkSequentialDiff(b *testing.B) {
+	set1, set2 := parallelInputs(200000)
+	b.ResetTimer()
+	for kk := 0; kk < b.N; kk++ {
+		set1.Diff(set2, intComparer[int])
+	}
+}
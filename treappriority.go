@@ -0,0 +1,77 @@
+package container
+
+import (
+	"hash/fnv"
+	"math/rand"
+
+	"golang.org/x/exp/constraints"
+)
+
+// PriorityFn returns the heap priority of a value inserted into a treap.
+//
+// Random priorities keep the treap balanced in expectation no matter the
+// insertion order. Hash priorities (see HashPriority) are just as balanced
+// for a decent hash, but also deterministic: two treaps holding the same
+// values have the exact same shape regardless of how they were built, which
+// makes structural comparisons and reproducible snapshots possible.
+type PriorityFn[T any] func(v T) int
+
+// RandomPriority returns a PriorityFn that draws priorities from math/rand.
+func RandomPriority[T any]() PriorityFn[T] {
+	return func(T) int {
+		return rand.Int()
+	}
+}
+
+// FixedPriority returns a PriorityFn that assigns the same priority to every
+// value. It is what TreapUnion does and it degrades the treap into a list
+// for sorted input, so it is mostly useful for tests.
+func FixedPriority[T any](priority int) PriorityFn[T] {
+	return func(T) int {
+		return priority
+	}
+}
+
+// HashPriority returns a deterministic PriorityFn derived from a hash of the
+// value. The hash is mixed again so that weak hashes (like the identity
+// function on integers) still produce well spread priorities.
+//
+// Two values with the same priority may be laid out in either order, so
+// equal contents only imply equal shapes while the hashes don't collide.
+func HashPriority[T any](hash func(v T) uint64) PriorityFn[T] {
+	return func(v T) int {
+		return int(mix64(hash(v)) >> 1)
+	}
+}
+
+// StringPriority returns a HashPriority for strings based on FNV-1a.
+func StringPriority() PriorityFn[string] {
+	return HashPriority(func(v string) uint64 {
+		h := fnv.New64a()
+		h.Write([]byte(v))
+		return h.Sum64()
+	})
+}
+
+// IntegerPriority returns a HashPriority for integer values.
+func IntegerPriority[T constraints.Integer]() PriorityFn[T] {
+	return HashPriority(func(v T) uint64 {
+		return uint64(v)
+	})
+}
+
+// mix64 is the splitmix64 finalizer.
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// Insert returns a new treap with v added, using p to pick the priority of
+// the new node. If v is already present it is replaced.
+func (n *Treap[T]) Insert(v T, c CompareFn[T], p PriorityFn[T]) *Treap[T] {
+	return n.Union(newTreap(v, p(v), nil, nil, nil), c, true)
+}
@@ -0,0 +1,110 @@
+package container_test
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/gabstv/container"
+)
+
+func TestHashPriorityShape(t *testing.T) {
+	rand.Seed(42)
+
+	c, p := intComparer[int], container.IntegerPriority[int]()
+	input := rand.Perm(2000)
+
+	var inserted *container.Treap[int]
+	for _, v := range input {
+		inserted = inserted.Insert(v, c, p)
+	}
+	built := container.TreapFromSlice(input, c, p)
+	if !reflect.DeepEqual(inserted, built) {
+		t.Fatal("same contents produced different shapes")
+	}
+	if !isHeapOrdered(built) {
+		t.Fatal("hash priorities are not heap ordered")
+	}
+
+	// every set operation must land on the canonical shape too
+	evens, odds := []int{}, []int{}
+	for _, v := range input {
+		if v%2 == 0 {
+			evens = append(evens, v)
+		} else {
+			odds = append(odds, v)
+		}
+	}
+	evenTreap := container.TreapFromSlice(evens, c, p)
+	oddTreap := container.TreapFromSlice(odds, c, p)
+
+	if !reflect.DeepEqual(evenTreap.Union(oddTreap, c, false), built) {
+		t.Fatal("Union diverged from the canonical shape")
+	}
+	if !reflect.DeepEqual(built.Diff(oddTreap, c), evenTreap) {
+		t.Fatal("Diff diverged from the canonical shape")
+	}
+	if !reflect.DeepEqual(built.Intersection(evenTreap, c), evenTreap) {
+		t.Fatal("Intersection diverged from the canonical shape")
+	}
+
+	deleted := built
+	for _, v := range odds {
+		deleted = deleted.Delete(v, c)
+	}
+	if !reflect.DeepEqual(deleted, evenTreap) {
+		t.Fatal("Delete diverged from the canonical shape")
+	}
+}
+
+func TestTreapUnionPriorities(t *testing.T) {
+	c, p := intComparer[int], container.IntegerPriority[int]()
+	input := []int{5, 3, 8, 1, 4, 7, 9, 2, 6, 3}
+
+	x := container.TreapUnionFunc(c, p, input...)
+	if !sameShape(x, container.TreapFromSlice(input, c, p)) {
+		t.Fatal("TreapUnionFunc diverged from the canonical shape")
+	}
+	container.AssertTreap(t, x, c, 1, 2, 3, 4, 5, 6, 7, 8, 9)
+
+	random := container.TreapUnionFunc(c, nil, input...)
+	container.AssertTreap(t, random, c, 1, 2, 3, 4, 5, 6, 7, 8, 9)
+	priorities := map[int]bool{}
+	for kk := 0; kk < random.Len(); kk++ {
+		priorities[random.At(kk).Priority] = true
+	}
+	if len(priorities) == 1 {
+		t.Fatal("TreapUnionFunc gave every item the same priority")
+	}
+
+	fixed := container.TreapUnion(c, 7, input...)
+	container.AssertTreap(t, fixed, c, 1, 2, 3, 4, 5, 6, 7, 8, 9)
+	for kk := 0; kk < fixed.Len(); kk++ {
+		if fixed.At(kk).Priority != 7 {
+			t.Fatal("TreapUnion diverged from its priority")
+		}
+	}
+}
+
+func TestStringPriority(t *testing.T) {
+	p := container.StringPriority()
+	if p("alpha") != p("alpha") || p("alpha") == p("bravo") {
+		t.Fatal("StringPriority is not deterministic")
+	}
+	if p("alpha") < 0 {
+		t.Fatal("StringPriority returned a negative priority")
+	}
+	if container.FixedPriority[string](7)("alpha") != 7 {
+		t.Fatal("FixedPriority diverged")
+	}
+}
+
+// sameShape reports whether both treaps hold the same values and priorities
+// laid out in the same way.
+func sameShape[T comparable](a, b *container.Treap[T]) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Value == b.Value && a.Priority == b.Priority && a.Len() == b.Len() &&
+		sameShape(a.Left, b.Left) && sameShape(a.Right, b.Right)
+}
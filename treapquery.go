@@ -0,0 +1,133 @@
+package container
+
+// RangeBounds tells Range which ends of the interval are inclusive.
+type RangeBounds uint8
+
+const (
+	// RangeOpen excludes both ends: lo < v < hi.
+	RangeOpen RangeBounds = 0
+	// RangeIncludeLow includes the lower end: lo <= v.
+	RangeIncludeLow RangeBounds = 1 << 0
+	// RangeIncludeHigh includes the upper end: v <= hi.
+	RangeIncludeHigh RangeBounds = 1 << 1
+	// RangeClosed includes both ends: lo <= v <= hi.
+	RangeClosed = RangeIncludeLow | RangeIncludeHigh
+)
+
+// Min returns the node with the smallest value, or nil if the treap is empty.
+func (n *Treap[T]) Min() *Treap[T] {
+	if n == nil {
+		return nil
+	}
+	for n.Left != nil {
+		n = n.Left
+	}
+	return n
+}
+
+// Max returns the node with the largest value, or nil if the treap is empty.
+func (n *Treap[T]) Max() *Treap[T] {
+	if n == nil {
+		return nil
+	}
+	for n.Right != nil {
+		n = n.Right
+	}
+	return n
+}
+
+// Floor returns the node with the largest value that compares less-than or
+// equal to v, or nil if there is none.
+func (n *Treap[T]) Floor(v T, c CompareFn[T]) *Treap[T] {
+	var found *Treap[T]
+	for n != nil {
+		diff := c(n.Value, v)
+		switch {
+		case diff == 0:
+			return n
+		case diff < 0:
+			found = n
+			n = n.Right
+		default:
+			n = n.Left
+		}
+	}
+	return found
+}
+
+// Ceiling returns the node with the smallest value that compares
+// greater-than or equal to v, or nil if there is none.
+func (n *Treap[T]) Ceiling(v T, c CompareFn[T]) *Treap[T] {
+	var found *Treap[T]
+	for n != nil {
+		diff := c(n.Value, v)
+		switch {
+		case diff == 0:
+			return n
+		case diff > 0:
+			found = n
+			n = n.Left
+		default:
+			n = n.Right
+		}
+	}
+	return found
+}
+
+// Predecessor returns the node with the largest value that compares
+// less-than v, or nil if there is none. v doesn't need to be in the treap.
+func (n *Treap[T]) Predecessor(v T, c CompareFn[T]) *Treap[T] {
+	var found *Treap[T]
+	for n != nil {
+		if c(n.Value, v) < 0 {
+			found = n
+			n = n.Right
+		} else {
+			n = n.Left
+		}
+	}
+	return found
+}
+
+// Successor returns the node with the smallest value that compares
+// greater-than v, or nil if there is none. v doesn't need to be in the
+// treap.
+func (n *Treap[T]) Successor(v T, c CompareFn[T]) *Treap[T] {
+	var found *Treap[T]
+	for n != nil {
+		if c(n.Value, v) > 0 {
+			found = n
+			n = n.Left
+		} else {
+			n = n.Right
+		}
+	}
+	return found
+}
+
+// Range calls fn in ascending order for every value between lo and hi. The
+// bounds control whether lo and hi themselves are included. Subtrees that
+// fall outside of the interval are not visited. Iteration stops when fn
+// returns false.
+func (n *Treap[T]) Range(lo, hi T, bounds RangeBounds, c CompareFn[T], fn func(v T) bool) {
+	n.visitRange(lo, hi, bounds, c, fn)
+}
+
+func (n *Treap[T]) visitRange(lo, hi T, bounds RangeBounds, c CompareFn[T], fn func(v T) bool) bool {
+	if n == nil {
+		return true
+	}
+	dlo, dhi := c(n.Value, lo), c(n.Value, hi)
+	if dlo > 0 && !n.Left.visitRange(lo, hi, bounds, c, fn) {
+		return false
+	}
+	aboveLo := dlo > 0 || (dlo == 0 && bounds&RangeIncludeLow != 0)
+	belowHi := dhi < 0 || (dhi == 0 && bounds&RangeIncludeHigh != 0)
+	if aboveLo && belowHi && !fn(n.Value) {
+		return false
+	}
+	if dhi < 0 {
+		return n.Right.visitRange(lo, hi, bounds, c, fn)
+	}
+	return true
+}
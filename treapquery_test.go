@@ -0,0 +1,88 @@
+package container_test
+
+import (
+	"testing"
+
+	"github.com/gabstv/container"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTreapMinMax(t *testing.T) {
+	var empty *container.Treap[int]
+	assert.Nil(t, empty.Min())
+	assert.Nil(t, empty.Max())
+
+	x := ToTreap([]int{50, 10, 40, 20, 30})
+	assert.Equal(t, 10, x.Min().Value)
+	assert.Equal(t, 50, x.Max().Value)
+}
+
+func TestTreapFloorCeiling(t *testing.T) {
+	x := ToTreap([]int{10, 20, 30, 40, 50})
+	c := intComparer[int]
+
+	assert.Equal(t, 20, x.Floor(20, c).Value)
+	assert.Equal(t, 20, x.Floor(25, c).Value)
+	assert.Nil(t, x.Floor(5, c))
+	assert.Equal(t, 50, x.Floor(100, c).Value)
+
+	assert.Equal(t, 20, x.Ceiling(20, c).Value)
+	assert.Equal(t, 30, x.Ceiling(25, c).Value)
+	assert.Equal(t, 10, x.Ceiling(5, c).Value)
+	assert.Nil(t, x.Ceiling(55, c))
+
+	assert.Equal(t, 10, x.Predecessor(20, c).Value)
+	assert.Equal(t, 20, x.Predecessor(25, c).Value)
+	assert.Nil(t, x.Predecessor(10, c))
+
+	assert.Equal(t, 30, x.Successor(20, c).Value)
+	assert.Equal(t, 30, x.Successor(25, c).Value)
+	assert.Nil(t, x.Successor(50, c))
+}
+
+func TestTreapRange(t *testing.T) {
+	x := ToTreap([]int{10, 20, 30, 40, 50, 60})
+	c := intComparer[int]
+
+	collect := func(lo, hi int, bounds container.RangeBounds) []int {
+		var out []int
+		x.Range(lo, hi, bounds, c, func(v int) bool {
+			out = append(out, v)
+			return true
+		})
+		return out
+	}
+
+	assert.Equal(t, []int{20, 30, 40}, collect(20, 40, container.RangeClosed))
+	assert.Equal(t, []int{30}, collect(20, 40, container.RangeOpen))
+	assert.Equal(t, []int{20, 30}, collect(20, 40, container.RangeIncludeLow))
+	assert.Equal(t, []int{30, 40}, collect(20, 40, container.RangeIncludeHigh))
+	assert.Equal(t, []int{20, 30, 40}, collect(15, 45, container.RangeOpen))
+	assert.Nil(t, collect(41, 49, container.RangeClosed))
+
+	var visited []int
+	x.Range(0, 100, container.RangeClosed, c, func(v int) bool {
+		visited = append(visited, v)
+		return len(visited) < 2
+	})
+	assert.Equal(t, []int{10, 20}, visited)
+
+	compared := 0
+	counting := func(left, right int) int {
+		compared++
+		return c(left, right)
+	}
+	large := ToTreap(rangeInts(0, 10000))
+	large.Range(100, 102, container.RangeClosed, counting, func(v int) bool {
+		return true
+	})
+	assert.Less(t, compared, 500, "Range should prune subtrees outside of the interval")
+}
+
+func rangeInts(from, to int) []int {
+	out := make([]int, 0, to-from)
+	for kk := from; kk < to; kk++ {
+		out = append(out, kk)
+	}
+	return out
+}
@@ -0,0 +1,257 @@
+package container
+
+// treapEdit is the owner token of a transient. It must not be zero sized,
+// as distinct zero sized allocations may share the same address.
+type treapEdit struct {
+	_ byte
+}
+
+// TransientTreap is a mutable view of a treap for batches of edits, along
+// the lines of Clojure transients. Nodes created by the transient are
+// owned by it and are modified in place by later edits instead of being
+// copied again, while nodes shared with the original treap are copied on
+// first write. The original treap is never modified.
+//
+// Call Persistent to get the resulting treap back. The transient must not
+// be used afterwards, and it is not safe for concurrent use.
+type TransientTreap[T any] struct {
+	root     *Treap[T]
+	edit     *treapEdit
+	compare  CompareFn[T]
+	priority PriorityFn[T]
+}
+
+// Transient returns a transient starting from the values of the treap. p
+// picks the priority of inserted values, it is random if nil.
+func (n *Treap[T]) Transient(c CompareFn[T], p PriorityFn[T]) *TransientTreap[T] {
+	if p == nil {
+		p = RandomPriority[T]()
+	}
+	return &TransientTreap[T]{
+		root:     n,
+		edit:     new(treapEdit),
+		compare:  c,
+		priority: p,
+	}
+}
+
+// Persistent freezes the transient and returns the resulting treap. The
+// transient panics if it is used again. Only the nodes created by the
+// transient are visited, so the cost is proportional to the edits.
+func (t *TransientTreap[T]) Persistent() *Treap[T] {
+	t.ensureEditable()
+	root := t.root.disown(t.edit)
+	t.root, t.edit = nil, nil
+	return root
+}
+
+// Len returns the number of values in the transient.
+func (t *TransientTreap[T]) Len() int {
+	t.ensureEditable()
+	return t.root.Len()
+}
+
+// Find finds the node with a matching value. The node must not be retained
+// across edits, as it may be modified in place.
+func (t *TransientTreap[T]) Find(v T) *Treap[T] {
+	t.ensureEditable()
+	return t.root.Find(v, t.compare)
+}
+
+// Insert adds v, replacing the value if it is already present.
+func (t *TransientTreap[T]) Insert(v T) {
+	t.ensureEditable()
+	leaf := &Treap[T]{Value: v, Priority: t.priority(v), size: 1, edit: t.edit}
+	t.root = t.root.unionT(leaf, t.compare, true, t.edit, nil)
+}
+
+// Delete removes v if it is present.
+func (t *TransientTreap[T]) Delete(v T) {
+	t.ensureEditable()
+	left, _, right := t.root.splitT(v, t.compare, t.edit, nil)
+	t.root = left.joinT(right, t.edit, nil)
+}
+
+// Union adds all values of other. See Treap.Union for the meaning of
+// overwrite. other is not modified.
+func (t *TransientTreap[T]) Union(other *Treap[T], overwrite bool) {
+	t.ensureEditable()
+	t.root = t.root.unionT(other, t.compare, overwrite, t.edit, nil)
+}
+
+// Intersection keeps only the values that are also present in other.
+func (t *TransientTreap[T]) Intersection(other *Treap[T]) {
+	t.ensureEditable()
+	t.root = t.root.intersectionT(other, t.compare, t.edit, nil)
+}
+
+// Diff removes all the values that are present in other.
+func (t *TransientTreap[T]) Diff(other *Treap[T]) {
+	t.ensureEditable()
+	t.root = t.root.diffT(other, t.compare, t.edit, nil)
+}
+
+func (t *TransientTreap[T]) ensureEditable() {
+	if t.edit == nil {
+		panic("container: transient treap used after Persistent")
+	}
+}
+
+// editable returns n itself if it is owned by edit, or a copy of it that
+// is.
+func (n *Treap[T]) editable(edit *treapEdit) *Treap[T] {
+	if n.edit == edit {
+		return n
+	}
+	cp := *n
+	cp.edit = edit
+	return &cp
+}
+
+// splitT is Split, reusing the nodes owned by edit.
+func (n *Treap[T]) splitT(v T, c CompareFn[T], edit *treapEdit, u treapUpdate[T]) (left, mid, right *Treap[T]) {
+	var path []*Treap[T]
+	leftp, rightp := &left, &right
+	for {
+		if n == nil {
+			*leftp = nil
+			*rightp = nil
+			fixPath(path, u)
+			return left, nil, right
+		}
+
+		root := n.editable(edit)
+		path = append(path, root)
+		diff := c(root.Value, v)
+		switch {
+		case diff < 0:
+			*leftp = root
+			leftp = &root.Right
+			n = root.Right
+		case diff > 0:
+			*rightp = root
+			rightp = &root.Left
+			n = root.Left
+		default:
+			*leftp = root.Left
+			*rightp = root.Right
+			root.Left, root.Right = nil, nil
+			fixPath(path, u)
+			return left, root, right
+		}
+	}
+}
+
+// joinT is join, reusing the nodes owned by edit.
+func (n *Treap[T]) joinT(other *Treap[T], edit *treapEdit, u treapUpdate[T]) *Treap[T] {
+	var path []*Treap[T]
+	var result *Treap[T]
+	resultp := &result
+	for {
+		if n == nil {
+			*resultp = other
+			fixPath(path, u)
+			return result
+		}
+		if other == nil {
+			*resultp = n
+			fixPath(path, u)
+			return result
+		}
+
+		if n.Priority >= other.Priority {
+			root := n.editable(edit)
+			path = append(path, root)
+			*resultp = root
+			resultp = &root.Right
+			n = root.Right
+		} else {
+			root := other.editable(edit)
+			path = append(path, root)
+			*resultp = root
+			resultp = &root.Left
+			other = root.Left
+		}
+	}
+}
+
+// unionT is Union, reusing the nodes owned by edit.
+func (n *Treap[T]) unionT(other *Treap[T], c CompareFn[T], overwrite bool, edit *treapEdit, u treapUpdate[T]) *Treap[T] {
+	if n == nil {
+		return other
+	}
+	if other == nil {
+		return n
+	}
+
+	if n.Priority < other.Priority {
+		other, n, overwrite = n, other, !overwrite
+	}
+
+	left, dupe, right := other.splitT(n.Value, c, edit, u)
+	root := n.editable(edit)
+	if overwrite && dupe != nil {
+		root.Value = dupe.Value
+	}
+	root.Left = root.Left.unionT(left, c, overwrite, edit, u)
+	root.Right = root.Right.unionT(right, c, overwrite, edit, u)
+	return root.fix(u)
+}
+
+// intersectionT is Intersection, reusing the nodes owned by edit.
+func (n *Treap[T]) intersectionT(other *Treap[T], c CompareFn[T], edit *treapEdit, u treapUpdate[T]) *Treap[T] {
+	if n == nil || other == nil {
+		return nil
+	}
+
+	if n.Priority < other.Priority {
+		n, other = other, n
+	}
+
+	left, found, right := other.splitT(n.Value, c, edit, u)
+	left = n.Left.intersectionT(left, c, edit, u)
+	right = n.Right.intersectionT(right, c, edit, u)
+
+	// both left and right only hold nodes owned by edit at this point
+	if found == nil {
+		return left.joinT(right, edit, u)
+	}
+
+	root := n.editable(edit)
+	root.Left, root.Right = left, right
+	return root.fix(u)
+}
+
+// disown clears the owner token of the nodes owned by edit, so that a
+// treap built as a transient ends up like any persistent one.
+func (n *Treap[T]) disown(edit *treapEdit) *Treap[T] {
+	if n != nil && n.edit == edit {
+		n.edit = nil
+		n.Left.disown(edit)
+		n.Right.disown(edit)
+	}
+	return n
+}
+
+// diffT is Diff, reusing the nodes owned by edit.
+func (n *Treap[T]) diffT(other *Treap[T], c CompareFn[T], edit *treapEdit, u treapUpdate[T]) *Treap[T] {
+	if n == nil || other == nil {
+		return n
+	}
+
+	if n.Priority >= other.Priority {
+		left, dupe, right := other.splitT(n.Value, c, edit, u)
+		root := n.editable(edit)
+		left, right = root.Left.diffT(left, c, edit, u), root.Right.diffT(right, c, edit, u)
+		if dupe != nil {
+			return left.joinT(right, edit, u)
+		}
+		root.Left, root.Right = left, right
+		return root.fix(u)
+	}
+
+	left, _, right := n.splitT(other.Value, c, edit, u)
+	left = left.diffT(other.Left, c, edit, u)
+	right = right.diffT(other.Right, c, edit, u)
+	return left.joinT(right, edit, u)
+}
@@ -0,0 +1,89 @@
+package container_test
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/gabstv/container"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransientTreap(t *testing.T) {
+	rand.Seed(42)
+
+	c, p := intComparer[int], container.IntegerPriority[int]()
+	base := container.TreapFromSorted(rangeInts(0, 1000), p)
+	snapshot := ToArray(base)
+
+	tr := base.Transient(c, p)
+	expected := base
+	for kk := 0; kk < 2000; kk++ {
+		v := rand.Intn(3000)
+		if kk%3 == 0 {
+			tr.Delete(v)
+			expected = expected.Delete(v, c)
+		} else {
+			tr.Insert(v)
+			expected = expected.Insert(v, c, p)
+		}
+	}
+	assert.Equal(t, expected.Len(), tr.Len())
+	assert.NotNil(t, tr.Find(expected.Min().Value))
+
+	other := container.TreapFromSlice(rand.Perm(1500), c, p)
+	tr.Union(other, false)
+	expected = expected.Union(other, c, false)
+	tr.Diff(container.TreapFromSorted(rangeInts(500, 700), p))
+	expected = expected.Diff(container.TreapFromSorted(rangeInts(500, 700), p), c)
+	tr.Intersection(container.TreapFromSorted(rangeInts(100, 2000), p))
+	expected = expected.Intersection(container.TreapFromSorted(rangeInts(100, 2000), p), c)
+
+	result := tr.Persistent()
+	assert.Equal(t, ToArray(expected), ToArray(result))
+	assert.Equal(t, expected.Len(), result.Len())
+	assert.True(t, isHeapOrdered(result))
+	assert.Equal(t, snapshot, ToArray(base), "the original treap was modified")
+
+	assert.True(t, reflect.DeepEqual(expected, result))
+
+	assert.Panics(t, func() {
+		tr.Insert(1)
+	})
+}
+
+func TestTransientTreapReusesNodes(t *testing.T) {
+	c := intComparer[int]
+	tr := (*container.Treap[int])(nil).Transient(c, nil)
+	tr.Insert(1)
+	tr.Insert(2)
+	first := tr.Find(1)
+	tr.Insert(3)
+	tr.Delete(2)
+	assert.Same(t, first, tr.Find(1))
+	assert.Equal(t, []int{1, 3}, ToArray(tr.Persistent()))
+}
+
+func BenchmarkTransientInsert(b *testing.B) {
+	rand.Seed(42)
+	input := rand.Perm(10000)
+	for kk := 0; kk < b.N; kk++ {
+		tr := (*container.Treap[int])(nil).Transient(intComparer[int], nil)
+		for _, v := range input {
+			tr.Insert(v)
+		}
+		tr.Persistent()
+	}
+}
+
+func BenchmarkPersistentInsert(b *testing.B) {
+	rand.Seed(42)
+	input := rand.Perm(10000)
+	p := container.RandomPriority[int]()
+	for kk := 0; kk < b.N; kk++ {
+		var x *container.Treap[int]
+		for _, v := range input {
+			x = x.Insert(v, intComparer[int], p)
+		}
+	}
+}
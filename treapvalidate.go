@@ -0,0 +1,65 @@
+package container
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrTreapOrder    = errors.New("value out of order")
+	ErrTreapPriority = errors.New("priority higher than the parent's")
+	ErrTreapSize     = errors.New("wrong subtree size")
+)
+
+// TreapError is returned by Validate. It holds the first node that breaks
+// the treap invariants, in pre-order.
+type TreapError[T any] struct {
+	Node *Treap[T]
+	Err  error
+}
+
+func (e *TreapError[T]) Error() string {
+	return fmt.Sprintf("treap node %v: %v", e.Node.Value, e.Err)
+}
+
+func (e *TreapError[T]) Unwrap() error {
+	return e.Err
+}
+
+// Validate checks that the treap is a binary search tree of distinct
+// values, that priorities are in heap order (no child has a higher priority
+// than its parent) and that the tracked subtree sizes are right. It returns
+// a *TreapError for the first node that doesn't, or nil.
+//
+// This is mostly useful for treaps built by hand, as the methods of this
+// package always keep the invariants.
+func (n *Treap[T]) Validate(c CompareFn[T]) error {
+	_, err := n.validate(c, nil, nil)
+	return err
+}
+
+// validate returns the number of values in the subtree. lo and hi are the
+// exclusive bounds of the values allowed in it, nil when unbounded.
+func (n *Treap[T]) validate(c CompareFn[T], lo, hi *T) (int, error) {
+	if n == nil {
+		return 0, nil
+	}
+	if (lo != nil && c(n.Value, *lo) <= 0) || (hi != nil && c(n.Value, *hi) >= 0) {
+		return 0, &TreapError[T]{n, ErrTreapOrder}
+	}
+	if (n.Left != nil && n.Left.Priority > n.Priority) || (n.Right != nil && n.Right.Priority > n.Priority) {
+		return 0, &TreapError[T]{n, ErrTreapPriority}
+	}
+	left, err := n.Left.validate(c, lo, &n.Value)
+	if err != nil {
+		return 0, err
+	}
+	right, err := n.Right.validate(c, &n.Value, hi)
+	if err != nil {
+		return 0, err
+	}
+	if n.size != 0 && n.size != 1+left+right {
+		return 0, &TreapError[T]{n, ErrTreapSize}
+	}
+	return 1 + left + right, nil
+}
@@ -0,0 +1,176 @@
+package container_test
+
+import (
+	"errors"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/gabstv/container"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTreapValidate(t *testing.T) {
+	c := intComparer[int]
+	assert.NoError(t, (*container.Treap[int])(nil).Validate(c))
+	assert.NoError(t, ToTreap(rand.Perm(1000)).Validate(c))
+
+	bad := &container.Treap[int]{Value: 2, Priority: 10,
+		Left:  &container.Treap[int]{Value: 3, Priority: 5},
+		Right: &container.Treap[int]{Value: 4, Priority: 5},
+	}
+	err := bad.Validate(c)
+	assert.ErrorIs(t, err, container.ErrTreapOrder)
+	var terr *container.TreapError[int]
+	assert.True(t, errors.As(err, &terr))
+	assert.Equal(t, 3, terr.Node.Value)
+
+	bad.Left.Value = 1
+	assert.NoError(t, bad.Validate(c))
+	bad.Right.Priority = 20
+	err = bad.Validate(c)
+	assert.ErrorIs(t, err, container.ErrTreapPriority)
+	assert.True(t, errors.As(err, &terr))
+	assert.Equal(t, 2, terr.Node.Value)
+
+	dupe := &container.Treap[int]{Value: 2, Priority: 10,
+		Right: &container.Treap[int]{Value: 2, Priority: 5},
+	}
+	assert.ErrorIs(t, dupe.Validate(c), container.ErrTreapOrder)
+
+	// sizes are tracked, so moving nodes around by hand is caught
+	x := container.TreapFromSorted([]int{1, 2, 3}, container.FixedPriority[int](0))
+	x.Right.Right.Right = &container.Treap[int]{Value: 4}
+	assert.ErrorIs(t, x.Validate(c), container.ErrTreapSize)
+}
+
+// fuzzTreap builds a treap from the bytes of data, drawing priorities from
+// r, along with the sorted and deduplicated model of its values.
+func fuzzTreap(data []byte, r *rand.Rand) (*container.Treap[int], []int) {
+	var t *container.Treap[int]
+	seen := map[int]bool{}
+	model := []int{}
+	p := func(int) int {
+		return r.Intn(1 << 16)
+	}
+	for _, b := range data {
+		t = t.Insert(int(b), intComparer[int], p)
+		if !seen[int(b)] {
+			seen[int(b)] = true
+			model = append(model, int(b))
+		}
+	}
+	sort.Ints(model)
+	return t, model
+}
+
+func FuzzTreapOperations(f *testing.F) {
+	f.Add([]byte{}, []byte{}, byte(0), int64(0))
+	f.Add([]byte{1, 2, 3}, []byte{2, 3, 4}, byte(2), int64(1))
+	f.Add([]byte("the quick brown fox"), []byte("jumps over the lazy dog"), byte('o'), int64(42))
+	f.Add([]byte{9, 8, 7, 6, 5, 4, 3, 2, 1}, []byte{1, 1, 1}, byte(5), int64(7))
+
+	f.Fuzz(func(t *testing.T, a, b []byte, pivot byte, seed int64) {
+		c := intComparer[int]
+		r := rand.New(rand.NewSource(seed))
+		x, xs := fuzzTreap(a, r)
+		y, ys := fuzzTreap(b, r)
+		xcopy := ToArray(x)
+
+		check := func(name string, got *container.Treap[int], want []int) {
+			t.Helper()
+			if err := got.Validate(c); err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			if len(want) == 0 {
+				want = nil
+			}
+			if !reflect.DeepEqual(ToArray(got), want) {
+				t.Fatalf("%s: got %v, want %v", name, ToArray(got), want)
+			}
+			if got.Len() != len(want) {
+				t.Fatalf("%s: Len is %d, want %d", name, got.Len(), len(want))
+			}
+		}
+
+		check("x", x, xs)
+		check("y", y, ys)
+		check("Union", x.Union(y, c, false), modelUnion(xs, ys))
+		check("Intersection", x.Intersection(y, c), modelIntersection(xs, ys))
+		check("Diff", x.Diff(y, c), modelDiff(xs, ys))
+		check("Delete", x.Delete(int(pivot), c), modelDiff(xs, []int{int(pivot)}))
+
+		left, mid, right := x.Split(int(pivot), c)
+		i := sort.SearchInts(xs, int(pivot))
+		j := i
+		if j < len(xs) && xs[j] == int(pivot) {
+			j++
+			if mid == nil || mid.Value != int(pivot) {
+				t.Fatal("Split lost the pivot")
+			}
+		} else if mid != nil {
+			t.Fatal("Split found a missing pivot")
+		}
+		check("Split left", left, xs[:i])
+		check("Split right", right, xs[j:])
+
+		k := int(pivot) % (len(xs) + 1)
+		left, right = x.SplitAt(k)
+		check("SplitAt left", left, xs[:k])
+		check("SplitAt right", right, xs[k:])
+
+		tr := x.Transient(c, nil)
+		tr.Union(y, false)
+		tr.Delete(int(pivot))
+		check("Transient", tr.Persistent(), modelDiff(modelUnion(xs, ys), []int{int(pivot)}))
+
+		if !reflect.DeepEqual(ToArray(x), xcopy) {
+			t.Fatal("the operations modified their input")
+		}
+	})
+}
+
+func modelUnion(a, b []int) []int {
+	return modelMerge(a, b, func(ina, inb bool) bool {
+		return ina || inb
+	})
+}
+
+func modelIntersection(a, b []int) []int {
+	return modelMerge(a, b, func(ina, inb bool) bool {
+		return ina && inb
+	})
+}
+
+func modelDiff(a, b []int) []int {
+	return modelMerge(a, b, func(ina, inb bool) bool {
+		return ina && !inb
+	})
+}
+
+// modelMerge walks two sorted slices and keeps the values for which keep
+// returns true.
+func modelMerge(a, b []int, keep func(ina, inb bool) bool) []int {
+	out := []int{}
+	for len(a) > 0 || len(b) > 0 {
+		switch {
+		case len(b) == 0 || (len(a) > 0 && a[0] < b[0]):
+			if keep(true, false) {
+				out = append(out, a[0])
+			}
+			a = a[1:]
+		case len(a) == 0 || b[0] < a[0]:
+			if keep(false, true) {
+				out = append(out, b[0])
+			}
+			b = b[1:]
+		default:
+			if keep(true, true) {
+				out = append(out, a[0])
+			}
+			a, b = a[1:], b[1:]
+		}
+	}
+	return out
+}
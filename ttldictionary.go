@@ -0,0 +1,247 @@
+package container
+
+import (
+	"sync"
+	"time"
+)
+
+// TTLConfig configures a TTLDictionary.
+type TTLConfig[KT comparable, VT any] struct {
+	// TTL is how long entries set with Set live. Zero means they never
+	// expire.
+	TTL time.Duration
+	// JanitorInterval is how often the janitor deletes expired entries.
+	// Zero means there is no janitor and entries are only deleted when
+	// they are accessed or by DeleteExpired.
+	JanitorInterval time.Duration
+	// OnEvict, if set, is called with every expired entry when it gets
+	// deleted. It isn't called for entries deleted by Remove, Pop or
+	// Clear. It runs without holding the lock, so it may use the
+	// dictionary.
+	OnEvict func(k KT, v VT)
+	// Clock tells the current time, time.Now if nil.
+	Clock func() time.Time
+	// After schedules the runs of the janitor like time.After, which is
+	// used if nil. Tests can pass one driven by the same fake time as
+	// Clock.
+	After func(d time.Duration) <-chan time.Time
+}
+
+// TTLDictionary is a thread-safe dictionary whose entries expire after some
+// time. Expired entries are never returned; they are deleted when accessed,
+// by DeleteExpired, or by a background janitor.
+type TTLDictionary[KT comparable, VT any] struct {
+	lock    sync.RWMutex
+	m       map[KT]ttlEntry[VT]
+	ttl     time.Duration
+	onEvict func(k KT, v VT)
+	now     func() time.Time
+	after   func(d time.Duration) <-chan time.Time
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+type ttlEntry[VT any] struct {
+	value   VT
+	expires time.Time // zero if the entry never expires
+}
+
+func (e ttlEntry[VT]) expired(now time.Time) bool {
+	return !e.expires.IsZero() && !now.Before(e.expires)
+}
+
+// NewTTLDictionary returns an empty dictionary. If cfg has a janitor
+// interval, Close must be called to stop the janitor.
+func NewTTLDictionary[KT comparable, VT any](cfg TTLConfig[KT, VT]) *TTLDictionary[KT, VT] {
+	m := &TTLDictionary[KT, VT]{
+		m:       make(map[KT]ttlEntry[VT]),
+		ttl:     cfg.TTL,
+		onEvict: cfg.OnEvict,
+		now:     cfg.Clock,
+		after:   cfg.After,
+	}
+	if m.now == nil {
+		m.now = time.Now
+	}
+	if m.after == nil {
+		m.after = time.After
+	}
+	if cfg.JanitorInterval > 0 {
+		m.stop = make(chan struct{})
+		m.done = make(chan struct{})
+		go m.janitor(cfg.JanitorInterval)
+	}
+	return m
+}
+
+func (m *TTLDictionary[KT, VT]) janitor(interval time.Duration) {
+	defer close(m.done)
+	for {
+		select {
+		case <-m.after(interval):
+			m.DeleteExpired()
+		case <-m.stop:
+			return
+		}
+	}
+}
+
+// Close stops the janitor and waits for it to return. The dictionary can
+// still be used afterwards, expired entries are then only deleted when
+// accessed.
+func (m *TTLDictionary[KT, VT]) Close() error {
+	m.closeOnce.Do(func() {
+		if m.stop != nil {
+			close(m.stop)
+			<-m.done
+		}
+	})
+	return nil
+}
+
+// Set sets a key=value pair that expires after the default TTL.
+func (m *TTLDictionary[KT, VT]) Set(k KT, v VT) {
+	m.SetWithTTL(k, v, m.ttl)
+}
+
+// SetWithTTL sets a key=value pair that expires after ttl. If ttl is zero
+// or less the pair never expires.
+func (m *TTLDictionary[KT, VT]) SetWithTTL(k KT, v VT, ttl time.Duration) {
+	e := ttlEntry[VT]{value: v}
+	if ttl > 0 {
+		e.expires = m.now().Add(ttl)
+	}
+	m.store(k, e)
+}
+
+// store sets the entry of the key as is, keeping its expiry.
+func (m *TTLDictionary[KT, VT]) store(k KT, e ttlEntry[VT]) {
+	m.lock.Lock()
+	m.m[k] = e
+	m.lock.Unlock()
+}
+
+// lookup returns the entry of the key, deleting it if it expired.
+func (m *TTLDictionary[KT, VT]) lookup(k KT) (ttlEntry[VT], bool) {
+	now := m.now()
+	m.lock.RLock()
+	e, ok := m.m[k]
+	m.lock.RUnlock()
+	if !ok || !e.expired(now) {
+		return e, ok
+	}
+
+	m.lock.Lock()
+	// it may have been set again since
+	e, ok = m.m[k]
+	if !ok || !e.expired(now) {
+		m.lock.Unlock()
+		return e, ok
+	}
+	delete(m.m, k)
+	m.lock.Unlock()
+	if m.onEvict != nil {
+		m.onEvict(k, e.value)
+	}
+	return ttlEntry[VT]{}, false
+}
+
+// Get returns the value of the key, or the zero value if it isn't set or
+// expired.
+func (m *TTLDictionary[KT, VT]) Get(k KT) VT {
+	e, _ := m.lookup(k)
+	return e.value
+}
+
+// Contains returns true if the dictionary contains the key and it didn't
+// expire.
+func (m *TTLDictionary[KT, VT]) Contains(k KT) bool {
+	_, ok := m.lookup(k)
+	return ok
+}
+
+// Expiry returns when the key expires. ok is false if the key isn't set or
+// expired, and the time is zero if the key never expires.
+func (m *TTLDictionary[KT, VT]) Expiry(k KT) (expires time.Time, ok bool) {
+	e, ok := m.lookup(k)
+	return e.expires, ok
+}
+
+// Remove deletes the key from the dictionary. It returns false if the key
+// wasn't set or expired.
+func (m *TTLDictionary[KT, VT]) Remove(k KT) bool {
+	_, ok := m.Pop(k)
+	return ok
+}
+
+// Pop removes and returns the value of the key. It returns false if the key
+// wasn't set or expired; an expired entry is deleted without calling
+// OnEvict, as with Remove.
+func (m *TTLDictionary[KT, VT]) Pop(k KT) (VT, bool) {
+	m.lock.Lock()
+	e, ok := m.m[k]
+	delete(m.m, k)
+	m.lock.Unlock()
+	if ok && !e.expired(m.now()) {
+		return e.value, true
+	}
+	var zv VT
+	return zv, false
+}
+
+// Clear removes all keys.
+func (m *TTLDictionary[KT, VT]) Clear() {
+	m.lock.Lock()
+	m.m = make(map[KT]ttlEntry[VT])
+	m.lock.Unlock()
+}
+
+// DeleteExpired deletes the expired entries and returns how many there
+// were.
+func (m *TTLDictionary[KT, VT]) DeleteExpired() int {
+	now := m.now()
+	var evicted []KT
+	var values []VT
+	m.lock.Lock()
+	for k, e := range m.m {
+		if e.expired(now) {
+			delete(m.m, k)
+			evicted = append(evicted, k)
+			values = append(values, e.value)
+		}
+	}
+	m.lock.Unlock()
+	if m.onEvict != nil {
+		for i, k := range evicted {
+			m.onEvict(k, values[i])
+		}
+	}
+	return len(evicted)
+}
+
+// Each calls the given function for each key=value pair in the map that
+// didn't expire. It creates a copy of the map to iterate, so setting a key
+// inside the loop will not affect the iteration.
+func (m *TTLDictionary[KT, VT]) Each(fn func(KT, VT) bool) {
+	for k, v := range m.Map() {
+		if !fn(k, v) {
+			return
+		}
+	}
+}
+
+// Map returns a map copy of the entries that didn't expire.
+func (m *TTLDictionary[KT, VT]) Map() map[KT]VT {
+	now := m.now()
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	m2 := make(map[KT]VT, len(m.m))
+	for k, e := range m.m {
+		if !e.expired(now) {
+			m2[k] = e.value
+		}
+	}
+	return m2
+}
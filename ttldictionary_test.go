@@ -0,0 +1,175 @@
+package container_test
+
+import (
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gabstv/container"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock is a clock that only moves when told to.
+type fakeClock struct {
+	lock    sync.Mutex
+	now     time.Time
+	waiters []fakeWaiter
+}
+
+type fakeWaiter struct {
+	at time.Time
+	c  chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	w := fakeWaiter{c.now.Add(d), make(chan time.Time, 1)}
+	c.waiters = append(c.waiters, w)
+	return w.c
+}
+
+// Waiters returns how many After channels are still pending.
+func (c *fakeClock) Waiters() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return len(c.waiters)
+}
+
+// Advance moves the clock and fires the After channels that are due.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.now = c.now.Add(d)
+	pending := c.waiters[:0]
+	for _, w := range c.waiters {
+		if c.now.Before(w.at) {
+			pending = append(pending, w)
+		} else {
+			w.c <- c.now
+		}
+	}
+	c.waiters = pending
+}
+
+func TestTTLDictionary(t *testing.T) {
+	clock := newFakeClock()
+	var evicted []string
+	d := container.NewTTLDictionary(container.TTLConfig[string, int]{
+		TTL:   time.Minute,
+		Clock: clock.Now,
+		After: clock.After,
+		OnEvict: func(k string, v int) {
+			evicted = append(evicted, k)
+		},
+	})
+	defer d.Close()
+
+	d.Set("a", 1)
+	d.SetWithTTL("b", 2, 10*time.Second)
+	d.SetWithTTL("forever", 3, 0)
+	assert.Equal(t, 1, d.Get("a"))
+	assert.Equal(t, 2, d.Get("b"))
+	exp, ok := d.Expiry("a")
+	assert.True(t, ok)
+	assert.Equal(t, clock.Now().Add(time.Minute), exp)
+	exp, ok = d.Expiry("forever")
+	assert.True(t, ok)
+	assert.True(t, exp.IsZero())
+
+	clock.Advance(10 * time.Second)
+	assert.False(t, d.Contains("b"))
+	assert.Equal(t, 0, d.Get("b"))
+	assert.Equal(t, []string{"b"}, evicted)
+	assert.Equal(t, map[string]int{"a": 1, "forever": 3}, d.Map())
+
+	// setting a key again renews it
+	d.Set("a", 4)
+	clock.Advance(50 * time.Second)
+	assert.Equal(t, 4, d.Get("a"))
+	clock.Advance(10 * time.Second)
+	assert.Equal(t, map[string]int{"forever": 3}, d.Map())
+	// popping an expired entry is not an eviction
+	_, ok = d.Pop("a")
+	assert.False(t, ok)
+	assert.Equal(t, []string{"b"}, evicted)
+	d.SetWithTTL("c", 5, time.Second)
+	clock.Advance(time.Second)
+	assert.Equal(t, 1, d.DeleteExpired())
+	assert.Equal(t, 0, d.DeleteExpired())
+	assert.Equal(t, []string{"b", "c"}, evicted)
+
+	assert.True(t, d.Remove("forever"))
+	assert.False(t, d.Remove("forever"))
+	d.Set("d", 6)
+	d.Clear()
+	assert.Empty(t, d.Map())
+	assert.Equal(t, []string{"b", "c"}, evicted)
+}
+
+func TestTTLDictionaryEach(t *testing.T) {
+	clock := newFakeClock()
+	d := container.NewTTLDictionary(container.TTLConfig[int, int]{Clock: clock.Now})
+	for i := 0; i < 10; i++ {
+		d.SetWithTTL(i, i, time.Duration(i+1)*time.Second)
+	}
+	clock.Advance(5 * time.Second)
+	keys := []int{}
+	d.Each(func(k, v int) bool {
+		keys = append(keys, k)
+		d.Set(k+100, v)
+		return true
+	})
+	sort.Ints(keys)
+	assert.Equal(t, []int{5, 6, 7, 8, 9}, keys)
+
+	count := 0
+	d.Each(func(k, v int) bool {
+		count++
+		return false
+	})
+	assert.Equal(t, 1, count)
+}
+
+func TestTTLDictionaryJanitor(t *testing.T) {
+	clock := newFakeClock()
+	evicted := make(chan string, 10)
+	d := container.NewTTLDictionary(container.TTLConfig[string, int]{
+		TTL:             time.Hour,
+		JanitorInterval: time.Minute,
+		Clock:           clock.Now,
+		After:           clock.After,
+		OnEvict: func(k string, v int) {
+			evicted <- k
+		},
+	})
+	d.Set("a", 1)
+	d.SetWithTTL("b", 2, 2*time.Hour)
+
+	// the janitor runs on the injected clock
+	waitFor(t, func() bool { return clock.Waiters() == 1 })
+	clock.Advance(time.Minute)
+	waitFor(t, func() bool { return clock.Waiters() == 1 })
+	assert.Empty(t, evicted)
+	clock.Advance(time.Hour)
+	assert.Equal(t, "a", <-evicted)
+	assert.NoError(t, d.Close())
+	assert.NoError(t, d.Close())
+
+	// once closed, entries only expire when accessed
+	clock.Advance(time.Hour)
+	assert.Empty(t, evicted)
+	assert.False(t, d.Contains("b"))
+	assert.Equal(t, "b", <-evicted)
+}